@@ -0,0 +1,138 @@
+package jwks
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	_defaultBackoffBase = 100 * time.Millisecond
+	_defaultBackoffMax  = 2 * time.Second
+)
+
+// Backoff computes delays between attempts of a single fetch operation.
+type Backoff interface {
+	// Next returns delay before the next attempt.
+	// If false is returned no more attempts should be made.
+	Next() (time.Duration, bool)
+}
+
+// RetryPolicy creates a new `Backoff` for every fetch operation.
+type RetryPolicy func() Backoff
+
+type backoffFunc func() (time.Duration, bool)
+
+func (f backoffFunc) Next() (time.Duration, bool) { return f() }
+
+// ConstantBackoff waits the same amount of time between attempts.
+func ConstantBackoff(d time.Duration) RetryPolicy {
+	return func() Backoff {
+		return backoffFunc(func() (time.Duration, bool) { return d, true })
+	}
+}
+
+// ExponentialBackoff doubles delay after every attempt starting from `base`
+// and never exceeding `max`. Each delay is randomized within [d/2, d) range
+// to spread out retries of concurrent clients.
+func ExponentialBackoff(base, max time.Duration) RetryPolicy {
+	return func() Backoff {
+		d := base
+		return backoffFunc(func() (time.Duration, bool) {
+			cur := d
+			if d < max {
+				d *= 2
+				if d > max {
+					d = max
+				}
+			}
+			return jitter(cur/2, cur), true
+		})
+	}
+}
+
+// DecorrelatedJitterBackoff picks every next delay randomly between `base`
+// and three times the previous delay, capped by `max`.
+func DecorrelatedJitterBackoff(base, max time.Duration) RetryPolicy {
+	return func() Backoff {
+		prev := base
+		return backoffFunc(func() (time.Duration, bool) {
+			d := jitter(base, prev*3)
+			if d > max {
+				d = max
+			}
+			prev = d
+			return d, true
+		})
+	}
+}
+
+// MaxElapsedTime stops retries of underlying policy once total time
+// spent on a fetch operation would exceed `d`.
+func MaxElapsedTime(policy RetryPolicy, d time.Duration) RetryPolicy {
+	return func() Backoff {
+		start := time.Now()
+		b := policy()
+		return backoffFunc(func() (time.Duration, bool) {
+			delay, ok := b.Next()
+			if !ok || time.Since(start)+delay > d {
+				return 0, false
+			}
+			return delay, true
+		})
+	}
+}
+
+// retryable reports whether request which failed with status may succeed if repeated.
+func retryable(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
+
+// retryAfter returns delay requested by `Retry-After` header
+// given either in seconds or as HTTP date.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// jitter returns random duration within [min, max) range.
+func jitter(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
+
+// sleep pauses current goroutine for at least `d` or until context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
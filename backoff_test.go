@@ -0,0 +1,86 @@
+package jwks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffDelays(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Policy jwks.RetryPolicy
+		Ops    int
+		Min    time.Duration
+		Max    time.Duration
+	}{
+		{
+			Name:   "Constant",
+			Policy: jwks.ConstantBackoff(50 * time.Millisecond),
+			Ops:    10,
+			Min:    50 * time.Millisecond,
+			Max:    50 * time.Millisecond,
+		},
+		{
+			Name:   "Exponential",
+			Policy: jwks.ExponentialBackoff(10*time.Millisecond, time.Second),
+			Ops:    20,
+			Min:    5 * time.Millisecond,
+			Max:    time.Second,
+		},
+		{
+			Name:   "DecorrelatedJitter",
+			Policy: jwks.DecorrelatedJitterBackoff(10*time.Millisecond, time.Second),
+			Ops:    20,
+			Min:    10 * time.Millisecond,
+			Max:    time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := tc.Policy()
+
+			for i := 0; i < tc.Ops; i++ {
+				d, ok := b.Next()
+				require.True(t, ok)
+				require.GreaterOrEqual(t, int64(d), int64(tc.Min))
+				require.LessOrEqual(t, int64(d), int64(tc.Max))
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffGrowth(t *testing.T) {
+	b := jwks.ExponentialBackoff(10*time.Millisecond, 80*time.Millisecond)()
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		80 * time.Millisecond,
+		80 * time.Millisecond,
+	}
+
+	for _, max := range expected {
+		d, ok := b.Next()
+		require.True(t, ok)
+		require.GreaterOrEqual(t, int64(d), int64(max/2))
+		require.Less(t, int64(d), int64(max))
+	}
+}
+
+func TestMaxElapsedTime(t *testing.T) {
+	b := jwks.MaxElapsedTime(jwks.ConstantBackoff(20*time.Millisecond), 50*time.Millisecond)()
+
+	d, ok := b.Next()
+	require.True(t, ok)
+	require.Equal(t, 20*time.Millisecond, d)
+
+	time.Sleep(40 * time.Millisecond)
+
+	_, ok = b.Next()
+	require.False(t, ok)
+}
@@ -0,0 +1,65 @@
+package jwks
+
+import (
+	"context"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// sharedCache stores keys of several managers in one lru cache.
+type sharedCache struct {
+	ns    string
+	cache *lru.Cache
+}
+
+func (sc *sharedCache) Add(_ context.Context, key *JWK) error {
+	if key.Kid == "" {
+		return ErrEmptyKeyID
+	}
+
+	sc.cache.Add(sc.ns+key.Kid, key)
+	return nil
+}
+
+func (sc *sharedCache) Get(_ context.Context, kid string) (*JWK, error) {
+	v, found := sc.cache.Get(sc.ns + kid)
+	if !found {
+		return nil, ErrCacheNotFound
+	}
+
+	key, ok := v.(*JWK)
+	if !ok {
+		return nil, ErrInvalidValue
+	}
+
+	return key, nil
+}
+
+func (sc *sharedCache) Remove(_ context.Context, kid string) error {
+	sc.cache.Remove(sc.ns + kid)
+	return nil
+}
+
+func (sc *sharedCache) Contains(_ context.Context, kid string) (bool, error) {
+	return sc.cache.Contains(sc.ns + kid), nil
+}
+
+func (sc *sharedCache) Len(_ context.Context) (int, error) {
+	n := 0
+	for _, k := range sc.cache.Keys() {
+		if strings.HasPrefix(k.(string), sc.ns) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (sc *sharedCache) Purge(_ context.Context) error {
+	for _, k := range sc.cache.Keys() {
+		if strings.HasPrefix(k.(string), sc.ns) {
+			sc.cache.Remove(k)
+		}
+	}
+	return nil
+}
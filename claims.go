@@ -0,0 +1,258 @@
+package jwks
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrInvalidClaim raises when registered claim is missing or has invalid type.
+	ErrInvalidClaim = errors.New("jwks: invalid claim")
+	// ErrTokenExpired raises when `exp` claim is in the past.
+	ErrTokenExpired = errors.New("jwks: token is expired")
+	// ErrTokenNotValidYet raises when `nbf` claim is in the future.
+	ErrTokenNotValidYet = errors.New("jwks: token is not valid yet")
+	// ErrTokenIssuedInFuture raises when `iat` claim is in the future.
+	ErrTokenIssuedInFuture = errors.New("jwks: token is issued in the future")
+	// ErrInvalidIssuer raises when `iss` claim is not expected.
+	ErrInvalidIssuer = errors.New("jwks: invalid issuer")
+	// ErrInvalidAudience raises when `aud` claim has no expected audience.
+	ErrInvalidAudience = errors.New("jwks: invalid audience")
+	// ErrInvalidSubject raises when `sub` claim is not expected.
+	ErrInvalidSubject = errors.New("jwks: invalid subject")
+)
+
+// Clock returns current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is an adapter to use ordinary function as `Clock`.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time { return f() }
+
+// RegisteredClaims holds claims defined by RFC 7519.
+type RegisteredClaims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt *time.Time
+	NotBefore *time.Time
+	IssuedAt  *time.Time
+	ID        string
+}
+
+// Registered extracts registered claims.
+func (c Claims) Registered() (*RegisteredClaims, error) {
+	var (
+		rc  RegisteredClaims
+		err error
+	)
+
+	if rc.Issuer, err = c.stringClaim("iss"); err != nil {
+		return nil, err
+	}
+	if rc.Subject, err = c.stringClaim("sub"); err != nil {
+		return nil, err
+	}
+	if rc.ID, err = c.stringClaim("jti"); err != nil {
+		return nil, err
+	}
+	if rc.Audience, err = c.audienceClaim(); err != nil {
+		return nil, err
+	}
+	if rc.ExpiresAt, err = c.timeClaim("exp"); err != nil {
+		return nil, err
+	}
+	if rc.NotBefore, err = c.timeClaim("nbf"); err != nil {
+		return nil, err
+	}
+	if rc.IssuedAt, err = c.timeClaim("iat"); err != nil {
+		return nil, err
+	}
+
+	return &rc, nil
+}
+
+func (c Claims) stringClaim(name string) (string, error) {
+	v, ok := c[name]
+	if !ok {
+		return "", nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", ErrInvalidClaim
+	}
+
+	return s, nil
+}
+
+func (c Claims) audienceClaim() ([]string, error) {
+	switch v := c["aud"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		aud := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, ErrInvalidClaim
+			}
+			aud = append(aud, s)
+		}
+		return aud, nil
+	default:
+		return nil, ErrInvalidClaim
+	}
+}
+
+func (c Claims) timeClaim(name string) (*time.Time, error) {
+	var secs float64
+
+	switch v := c[name].(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, ErrInvalidClaim
+		}
+		secs = f
+	case float64:
+		secs = v
+	case int64:
+		secs = float64(v)
+	case int:
+		secs = float64(v)
+	default:
+		return nil, ErrInvalidClaim
+	}
+
+	if math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return nil, ErrInvalidClaim
+	}
+
+	whole, frac := math.Modf(secs)
+	t := time.Unix(int64(whole), int64(frac*1e9))
+
+	return &t, nil
+}
+
+// ClaimsValidator validates registered claims of token.
+type ClaimsValidator struct {
+	clock    Clock
+	leeway   time.Duration
+	issuers  []string
+	audience []string
+	subject  string
+	required []string
+}
+
+// ClaimsOption is used for configuring claims validator.
+type ClaimsOption func(cv *ClaimsValidator)
+
+// WithClock sets time source. Default is system clock.
+func WithClock(c Clock) ClaimsOption {
+	return func(cv *ClaimsValidator) { cv.clock = c }
+}
+
+// WithLeeway defines allowed clock skew for time based claims. Default is `0`.
+func WithLeeway(d time.Duration) ClaimsOption {
+	return func(cv *ClaimsValidator) { cv.leeway = d }
+}
+
+// WithExpectedIssuer defines accepted `iss` values.
+func WithExpectedIssuer(issuers ...string) ClaimsOption {
+	return func(cv *ClaimsValidator) { cv.issuers = issuers }
+}
+
+// WithExpectedAudience defines accepted `aud` values. Token must contain at least one of them.
+func WithExpectedAudience(audience ...string) ClaimsOption {
+	return func(cv *ClaimsValidator) { cv.audience = audience }
+}
+
+// WithExpectedSubject defines accepted `sub` value.
+func WithExpectedSubject(sub string) ClaimsOption {
+	return func(cv *ClaimsValidator) { cv.subject = sub }
+}
+
+// WithRequiredClaims defines claims that must be present in token. Default is `exp`.
+func WithRequiredClaims(names ...string) ClaimsOption {
+	return func(cv *ClaimsValidator) { cv.required = names }
+}
+
+// NewClaimsValidator returns a new instance of `ClaimsValidator`.
+func NewClaimsValidator(opts ...ClaimsOption) *ClaimsValidator {
+	cv := &ClaimsValidator{
+		clock:    ClockFunc(time.Now),
+		required: []string{"exp"},
+	}
+
+	for _, opt := range opts {
+		opt(cv)
+	}
+
+	return cv
+}
+
+// Validate checks registered claims.
+func (cv *ClaimsValidator) Validate(claims Claims) error {
+	for _, name := range cv.required {
+		if _, ok := claims[name]; !ok {
+			return ErrInvalidClaim
+		}
+	}
+
+	rc, err := claims.Registered()
+	if err != nil {
+		return err
+	}
+
+	now := cv.clock.Now()
+
+	if rc.ExpiresAt != nil && !now.Before(rc.ExpiresAt.Add(cv.leeway)) {
+		return ErrTokenExpired
+	}
+
+	if rc.NotBefore != nil && now.Add(cv.leeway).Before(*rc.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+
+	if rc.IssuedAt != nil && now.Add(cv.leeway).Before(*rc.IssuedAt) {
+		return ErrTokenIssuedInFuture
+	}
+
+	if len(cv.issuers) > 0 && !containsAny(cv.issuers, rc.Issuer) {
+		return ErrInvalidIssuer
+	}
+
+	if len(cv.audience) > 0 && !containsAny(cv.audience, rc.Audience...) {
+		return ErrInvalidAudience
+	}
+
+	if cv.subject != "" && rc.Subject != cv.subject {
+		return ErrInvalidSubject
+	}
+
+	return nil
+}
+
+func containsAny(list []string, values ...string) bool {
+	for _, v := range values {
+		for _, item := range list {
+			if v == item {
+				return true
+			}
+		}
+	}
+	return false
+}
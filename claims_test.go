@@ -0,0 +1,160 @@
+package jwks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func fixedClock(t time.Time) jwks.Clock {
+	return jwks.ClockFunc(func() time.Time { return t })
+}
+
+func TestClaimsValidatorValidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	testCases := []struct {
+		Name    string
+		Claims  jwks.Claims
+		Options []jwks.ClaimsOption
+		Error   error
+	}{
+		{
+			Name: "OK",
+			Claims: jwks.Claims{
+				"iss": "https://example.com",
+				"sub": "user",
+				"aud": []interface{}{"api", "web"},
+				"exp": json.Number("1700000060"),
+				"nbf": json.Number("1699999990"),
+				"iat": json.Number("1699999990"),
+			},
+			Options: []jwks.ClaimsOption{
+				jwks.WithExpectedIssuer("https://example.com"),
+				jwks.WithExpectedAudience("api"),
+				jwks.WithExpectedSubject("user"),
+			},
+		},
+		{
+			Name:   "Expired",
+			Claims: jwks.Claims{"exp": json.Number("1700000000")},
+			Error:  jwks.ErrTokenExpired,
+		},
+		{
+			Name:    "ExpiredWithinLeeway",
+			Claims:  jwks.Claims{"exp": json.Number("1699999990")},
+			Options: []jwks.ClaimsOption{jwks.WithLeeway(time.Minute)},
+		},
+		{
+			Name:   "NotValidYet",
+			Claims: jwks.Claims{"exp": json.Number("1700000060"), "nbf": json.Number("1700000030")},
+			Error:  jwks.ErrTokenNotValidYet,
+		},
+		{
+			Name:   "IssuedInFuture",
+			Claims: jwks.Claims{"exp": json.Number("1700000060"), "iat": json.Number("1700000030")},
+			Error:  jwks.ErrTokenIssuedInFuture,
+		},
+		{
+			Name:    "WrongIssuer",
+			Claims:  jwks.Claims{"exp": json.Number("1700000060"), "iss": "https://evil.com"},
+			Options: []jwks.ClaimsOption{jwks.WithExpectedIssuer("https://example.com")},
+			Error:   jwks.ErrInvalidIssuer,
+		},
+		{
+			Name:    "WrongAudience",
+			Claims:  jwks.Claims{"exp": json.Number("1700000060"), "aud": "web"},
+			Options: []jwks.ClaimsOption{jwks.WithExpectedAudience("api")},
+			Error:   jwks.ErrInvalidAudience,
+		},
+		{
+			Name:    "MissingAudience",
+			Claims:  jwks.Claims{"exp": json.Number("1700000060")},
+			Options: []jwks.ClaimsOption{jwks.WithExpectedAudience("api")},
+			Error:   jwks.ErrInvalidAudience,
+		},
+		{
+			Name:    "WrongSubject",
+			Claims:  jwks.Claims{"exp": json.Number("1700000060"), "sub": "admin"},
+			Options: []jwks.ClaimsOption{jwks.WithExpectedSubject("user")},
+			Error:   jwks.ErrInvalidSubject,
+		},
+		{
+			Name:   "MissingExpiration",
+			Claims: jwks.Claims{"sub": "user"},
+			Error:  jwks.ErrInvalidClaim,
+		},
+		{
+			Name:    "NotRequiredExpiration",
+			Claims:  jwks.Claims{"sub": "user"},
+			Options: []jwks.ClaimsOption{jwks.WithRequiredClaims()},
+		},
+		{
+			Name:   "InvalidType",
+			Claims: jwks.Claims{"exp": "tomorrow"},
+			Error:  jwks.ErrInvalidClaim,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opts := append([]jwks.ClaimsOption{jwks.WithClock(fixedClock(now))}, tc.Options...)
+			err := jwks.NewClaimsValidator(opts...).Validate(tc.Claims)
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClaimsRegistered(t *testing.T) {
+	r := require.New(t)
+
+	claims := jwks.Claims{
+		"iss": "https://example.com",
+		"sub": "user",
+		"aud": "api",
+		"jti": "1",
+		"exp": json.Number("1700000000.5"),
+	}
+
+	rc, err := claims.Registered()
+	r.NoError(err)
+	r.Equal("https://example.com", rc.Issuer)
+	r.Equal("user", rc.Subject)
+	r.Equal([]string{"api"}, rc.Audience)
+	r.Equal("1", rc.ID)
+	r.True(rc.ExpiresAt.Equal(time.Unix(1700000000, 500000000)))
+	r.Nil(rc.NotBefore)
+	r.Nil(rc.IssuedAt)
+}
+
+func TestVerifierClaimsValidation(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(jwksHandler(keys.testKeys()...))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	verifier := jwks.NewVerifier(manager, jwks.WithClaimsValidator(
+		jwks.NewClaimsValidator(jwks.WithClock(fixedClock(time.Unix(1700000000, 0)))),
+	))
+
+	header := map[string]interface{}{"alg": "ES256", "kid": "ec"}
+
+	_, err = verifier.Verify(context.Background(), signToken(t, header, map[string]interface{}{"exp": 1700000060}, keys.ECDSA))
+	r.NoError(err)
+
+	_, err = verifier.Verify(context.Background(), signToken(t, header, map[string]interface{}{"exp": 1699999999}, keys.ECDSA))
+	r.ErrorIs(err, jwks.ErrTokenExpired)
+}
@@ -0,0 +1,176 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const _defaultDiscoveryInterval = 24 * time.Hour
+
+var (
+	// ErrDiscoveryFailed raises when issuer metadata cannot be fetched.
+	ErrDiscoveryFailed = errors.New("jwks: issuer metadata discovery failed")
+	// ErrIssuerMismatch raises when metadata issuer differs from requested one.
+	ErrIssuerMismatch = errors.New("jwks: issuer mismatch")
+
+	errMetadataNotFound = errors.New("jwks: metadata not found")
+)
+
+// metadata is a subset of OpenID Connect discovery document
+// and OAuth 2.0 authorization server metadata (RFC 8414).
+type metadata struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// discovery resolves JWKS URL from issuer metadata.
+type discovery struct {
+	mu       sync.Mutex
+	issuer   string
+	urls     []string
+	jwksURI  string
+	resolved time.Time
+}
+
+// NewManagerFromIssuer returns a new instance of `Manager` which resolves
+// JWKS URL using issuer's `/.well-known/openid-configuration` or
+// `/.well-known/oauth-authorization-server` metadata. Metadata is
+// re-resolved on interval defined by `WithDiscoveryInterval`.
+func NewManagerFromIssuer(issuer string, opts ...Option) (Manager, error) {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	mng := newManager(issuer, opts...)
+	mng.discovery = &discovery{
+		issuer: issuer,
+		urls:   metadataURLs(u),
+	}
+
+	return mng, nil
+}
+
+// metadataURLs returns well-known locations of issuer metadata in order of preference.
+func metadataURLs(issuer *url.URL) []string {
+	path := strings.TrimSuffix(issuer.Path, "/")
+
+	oidc := *issuer
+	oidc.Path = path + "/.well-known/openid-configuration"
+	oidc.RawPath = ""
+
+	// RFC 8414 inserts well-known suffix between host and path components.
+	oauth := *issuer
+	oauth.Path = "/.well-known/oauth-authorization-server" + path
+	oauth.RawPath = ""
+
+	return []string{oidc.String(), oauth.String()}
+}
+
+// resolve returns JWKS URL of issuer. Previously resolved URL is
+// returned if metadata has not expired or cannot be fetched.
+func (d *discovery) resolve(ctx context.Context, m *manager) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.jwksURI != "" && time.Since(d.resolved) < m.discoveryInterval {
+		return d.jwksURI, nil
+	}
+
+	uri, err := d.discover(ctx, m)
+	if err != nil {
+		if d.jwksURI != "" {
+			m.logger.Debug().Msgf("discovery failed with %v, using %s", err, d.jwksURI)
+			return d.jwksURI, nil
+		}
+		return "", err
+	}
+
+	if uri != d.jwksURI {
+		m.logger.Debug().Msgf("resolved jwks uri %s for %s", uri, d.issuer)
+	}
+
+	d.jwksURI = uri
+	d.resolved = time.Now()
+
+	return uri, nil
+}
+
+// discover fetches issuer metadata. Failures are described by `FetchError`
+// of the last metadata request.
+func (d *discovery) discover(ctx context.Context, m *manager) (string, error) {
+	fetchErr := &FetchError{}
+
+	for _, u := range d.urls {
+		m.logger.Debug().Msgf("fetching issuer metadata from %s", u)
+
+		fetchErr.URL = u
+		fetchErr.Attempts++
+
+		meta, status, err := fetchMetadata(ctx, m.client, u, m.maxBodySize)
+		fetchErr.StatusCode = status
+		if errors.Is(err, errMetadataNotFound) {
+			continue
+		}
+		if err != nil {
+			m.logger.Debug().Msgf("metadata request failed with %v", err)
+			fetchErr.Err = fmt.Errorf("%w: %v", ErrDiscoveryFailed, err)
+			return "", fetchErr
+		}
+
+		if meta.Issuer != d.issuer {
+			m.logger.Debug().Msgf("metadata issuer %s does not match %s", meta.Issuer, d.issuer)
+			fetchErr.Err = ErrIssuerMismatch
+			return "", fetchErr
+		}
+
+		jwksURI, err := url.Parse(meta.JWKSURI)
+		if err != nil || jwksURI.Scheme == "" || jwksURI.Host == "" {
+			m.logger.Debug().Msgf("metadata has invalid jwks_uri %q", meta.JWKSURI)
+			fetchErr.Err = ErrInvalidURL
+			return "", fetchErr
+		}
+
+		return jwksURI.String(), nil
+	}
+
+	fetchErr.Err = fmt.Errorf("%w: %v", ErrDiscoveryFailed, errMetadataNotFound)
+	return "", fetchErr
+}
+
+// fetchMetadata requests issuer metadata. Status code of response
+// is returned even if request has failed.
+func fetchMetadata(ctx context.Context, client *http.Client, u string, maxBodySize int64) (*metadata, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, resp.StatusCode, errMetadataNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("jwks: unexpected status code %d", resp.StatusCode)
+	}
+
+	var meta metadata
+	if err := decodeBody(resp, maxBodySize, &meta); err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	return &meta, resp.StatusCode, nil
+}
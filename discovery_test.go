@@ -0,0 +1,175 @@
+package jwks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func metadataHandler(issuer, jwksURI string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": jwksURI,
+		})
+		if err != nil {
+			http.Error(w, "Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
+
+func TestManagerFromIssuerInit(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Issuer string
+		Error  error
+	}{
+		{
+			Name:   "OK",
+			Issuer: "https://example.com",
+		},
+		{
+			Name:   "NoScheme",
+			Issuer: "example.com",
+			Error:  jwks.ErrInvalidURL,
+		},
+		{
+			Name:   "Malformed",
+			Issuer: "https://exa mple.com/%",
+			Error:  jwks.ErrInvalidURL,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			manager, err := jwks.NewManagerFromIssuer(tc.Issuer)
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, manager)
+			}
+		})
+	}
+}
+
+func TestManagerFromIssuerFetchKey(t *testing.T) {
+	_, pubKey, err := randomKeys()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name     string
+		Path     string
+		Metadata string
+		Issuer   func(base string) string
+		Error    error
+	}{
+		{
+			Name:     "OpenID",
+			Path:     "/tenant",
+			Metadata: "/tenant/.well-known/openid-configuration",
+		},
+		{
+			Name:     "OAuth",
+			Path:     "/tenant",
+			Metadata: "/.well-known/oauth-authorization-server/tenant",
+		},
+		{
+			Name:     "Mismatch",
+			Metadata: "/.well-known/openid-configuration",
+			Issuer:   func(base string) string { return base + "/other" },
+			Error:    jwks.ErrIssuerMismatch,
+		},
+		{
+			Name:     "NotFound",
+			Metadata: "/.well-known/unknown",
+			Error:    jwks.ErrDiscoveryFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			mux := http.NewServeMux()
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			issuer := ts.URL + tc.Path
+			metaIssuer := issuer
+			if tc.Issuer != nil {
+				metaIssuer = tc.Issuer(ts.URL)
+			}
+
+			mux.Handle(tc.Metadata, metadataHandler(metaIssuer, ts.URL+"/keys"))
+			mux.Handle("/keys", jwksHandler(testKey{"202101", pubKey}))
+
+			manager, err := jwks.NewManagerFromIssuer(issuer)
+			r.NoError(err)
+
+			key, err := manager.FetchKey(context.Background(), "202101")
+			if tc.Error != nil {
+				r.ErrorIs(err, tc.Error)
+			} else {
+				r.NoError(err)
+				r.Equal("202101", key.Kid)
+			}
+		})
+	}
+}
+
+func TestManagerFromIssuerRediscovery(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey1, err := randomKeys()
+	r.NoError(err)
+
+	_, pubKey2, err := randomKeys()
+	r.NoError(err)
+
+	var rotated, discoveries int32
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&discoveries, 1)
+		uri := ts.URL + "/keys/1"
+		if atomic.LoadInt32(&rotated) == 1 {
+			uri = ts.URL + "/keys/2"
+		}
+		metadataHandler(ts.URL, uri).ServeHTTP(w, r)
+	})
+	mux.Handle("/keys/1", jwksHandler(testKey{"202101", pubKey1}))
+	mux.Handle("/keys/2", jwksHandler(testKey{"202102", pubKey2}))
+
+	manager, err := jwks.NewManagerFromIssuer(ts.URL, jwks.WithDiscoveryInterval(50*time.Millisecond))
+	r.NoError(err)
+
+	_, err = manager.FetchKey(context.Background(), "202101")
+	r.NoError(err)
+
+	_, err = manager.FetchKey(context.Background(), "202102")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+	r.Equal(int32(1), atomic.LoadInt32(&discoveries))
+
+	atomic.StoreInt32(&rotated, 1)
+	time.Sleep(100 * time.Millisecond)
+
+	key, err := manager.FetchKey(context.Background(), "202102")
+	r.NoError(err)
+	r.Equal("202102", key.Kid)
+	r.Equal(int32(2), atomic.LoadInt32(&discoveries))
+}
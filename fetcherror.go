@@ -0,0 +1,39 @@
+package jwks
+
+import (
+	"fmt"
+	"time"
+)
+
+// FetchError describes failed key set download, including failed discovery.
+// It matches
+// `ErrConnectionFailed` with `errors.Is` and unwraps to its cause.
+type FetchError struct {
+	// URL is a location of key set.
+	URL string
+	// StatusCode is a status of last response or zero if none has been received.
+	StatusCode int
+	// Attempts is a number of sent requests.
+	Attempts int
+	// Elapsed is a duration of download including delays between attempts.
+	Elapsed time.Duration
+	// Err is a cause of last failed attempt.
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	msg := fmt.Sprintf("jwks: connection failed: %d attempts to %s in %s", e.Attempts, e.URL, e.Elapsed.Round(time.Millisecond))
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(", last status %d", e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns cause of failure.
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// Is reports whether target is `ErrConnectionFailed`.
+func (e *FetchError) Is(target error) bool { return target == ErrConnectionFailed }
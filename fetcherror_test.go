@@ -0,0 +1,158 @@
+package jwks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerFetchError(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	malformed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keys":}`))
+	}))
+	defer malformed.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	testCases := []struct {
+		Name       string
+		URL        string
+		StatusCode int
+		Attempts   int
+		Cause      func(err error) bool
+	}{
+		{
+			Name:       "ServiceUnavailable",
+			URL:        unavailable.URL,
+			StatusCode: http.StatusServiceUnavailable,
+			Attempts:   3,
+			Cause: func(err error) bool {
+				return err.Error() == "jwks: unexpected status code 503"
+			},
+		},
+		{
+			Name:     "ConnectionRefused",
+			URL:      closed.URL,
+			Attempts: 3,
+			Cause: func(err error) bool {
+				var netErr interface{ Timeout() bool }
+				return errors.As(err, &netErr)
+			},
+		},
+		{
+			Name:       "MalformedBody",
+			URL:        malformed.URL,
+			StatusCode: http.StatusOK,
+			Attempts:   1,
+			Cause: func(err error) bool {
+				var syntaxErr *json.SyntaxError
+				return errors.As(err, &syntaxErr)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mng, err := jwks.NewManager(tc.URL,
+				jwks.WithMaxRetries(3),
+				jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Millisecond)),
+			)
+			require.NoError(t, err)
+
+			_, err = mng.FetchKey(context.Background(), "kid")
+			require.ErrorIs(t, err, jwks.ErrConnectionFailed)
+
+			var fetchErr *jwks.FetchError
+			require.ErrorAs(t, err, &fetchErr)
+			require.Equal(t, tc.URL, fetchErr.URL)
+			require.Equal(t, tc.StatusCode, fetchErr.StatusCode)
+			require.Equal(t, tc.Attempts, fetchErr.Attempts)
+			require.Greater(t, int64(fetchErr.Elapsed), int64(0))
+			require.True(t, tc.Cause(fetchErr.Err), fetchErr.Err)
+			require.Contains(t, err.Error(), tc.URL)
+		})
+	}
+}
+
+func TestManagerFetchErrorTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	mng, err := jwks.NewManager(ts.URL,
+		jwks.WithFetchTimeout(200*time.Millisecond),
+		jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Second)),
+	)
+	require.NoError(t, err)
+
+	_, err = mng.FetchKey(context.Background(), "kid")
+	require.ErrorIs(t, err, jwks.ErrConnectionFailed)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var fetchErr *jwks.FetchError
+	require.ErrorAs(t, err, &fetchErr)
+	require.Equal(t, http.StatusServiceUnavailable, fetchErr.StatusCode)
+	require.Equal(t, 1, fetchErr.Attempts)
+}
+
+func TestManagerFetchErrorDiscovery(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		StatusCode int
+		Path       string
+		Attempts   int
+	}{
+		{
+			Name:       "NotFound",
+			StatusCode: http.StatusNotFound,
+			Path:       "/.well-known/oauth-authorization-server",
+			Attempts:   2,
+		},
+		{
+			Name:       "ServerError",
+			StatusCode: http.StatusInternalServerError,
+			Path:       "/.well-known/openid-configuration",
+			Attempts:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tc.StatusCode)
+			}))
+			defer ts.Close()
+
+			mng, err := jwks.NewManagerFromIssuer(ts.URL)
+			r.NoError(err)
+
+			_, err = mng.FetchKey(context.Background(), "kid")
+			r.ErrorIs(err, jwks.ErrConnectionFailed)
+			r.ErrorIs(err, jwks.ErrDiscoveryFailed)
+
+			var fetchErr *jwks.FetchError
+			r.ErrorAs(err, &fetchErr)
+			r.Equal(ts.URL+tc.Path, fetchErr.URL)
+			r.Equal(tc.StatusCode, fetchErr.StatusCode)
+			r.Equal(tc.Attempts, fetchErr.Attempts)
+			r.NotNil(errors.Unwrap(fetchErr))
+		})
+	}
+}
@@ -0,0 +1,14 @@
+module github.com/danikarik/jwks/grpcauth
+
+go 1.15
+
+require (
+	github.com/danikarik/jwks v0.0.0-20261017011336-3fd4c1691872
+	github.com/rakutentech/jwk-go v1.0.1
+	github.com/stretchr/testify v1.7.0
+	google.golang.org/grpc v1.35.0
+)
+
+// Local development against root module in the same repository.
+// Replace directives are ignored by consumers, which get required version.
+replace github.com/danikarik/jwks => ../
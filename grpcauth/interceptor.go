@@ -0,0 +1,139 @@
+// Package grpcauth provides grpc server interceptors authenticating
+// bearer tokens with `jwks.Verifier`.
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/danikarik/jwks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const _defaultMetadataKey = "authorization"
+
+type interceptor struct {
+	verifier *jwks.Verifier
+	key      string
+	skip     map[string]bool
+}
+
+// Option is used for configuring grpc interceptors.
+type Option func(i *interceptor)
+
+// WithMetadataKey sets metadata key holding token. Default is `authorization`.
+// Value of `authorization` key must use `Bearer` scheme, other keys may hold raw token.
+func WithMetadataKey(key string) Option {
+	return func(i *interceptor) { i.key = strings.ToLower(key) }
+}
+
+// WithSkipMethods disables authentication of given full method names
+// such as `/grpc.health.v1.Health/Check`.
+func WithSkipMethods(methods ...string) Option {
+	return func(i *interceptor) {
+		for _, m := range methods {
+			i.skip[m] = true
+		}
+	}
+}
+
+func newInterceptor(v *jwks.Verifier, opts ...Option) *interceptor {
+	i := &interceptor{
+		verifier: v,
+		key:      _defaultMetadataKey,
+		skip:     make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// UnaryServerInterceptor returns grpc interceptor which verifies token from
+// incoming metadata and stores it in context of unary request.
+func UnaryServerInterceptor(v *jwks.Verifier, opts ...Option) grpc.UnaryServerInterceptor {
+	i := newInterceptor(v, opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if i.skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, err := i.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns grpc interceptor which verifies token from
+// incoming metadata and stores it in context of stream.
+func StreamServerInterceptor(v *jwks.Verifier, opts ...Option) grpc.StreamServerInterceptor {
+	i := newInterceptor(v, opts...)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if i.skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		ctx, err := i.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (i *interceptor) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get(i.key)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "token not found")
+	}
+	if len(values) > 1 {
+		return nil, status.Error(codes.Unauthenticated, "multiple tokens provided")
+	}
+
+	raw := values[0]
+	if parts := strings.SplitN(raw, " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+		raw = strings.TrimSpace(parts[1])
+	} else if i.key == _defaultMetadataKey {
+		return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+	}
+
+	token, err := i.verifier.Verify(ctx, raw)
+	if err != nil {
+		// Keys are unavailable, so token cannot be judged. Cause is not
+		// exposed, because it describes internal infrastructure.
+		if errors.Is(err, jwks.ErrConnectionFailed) || errors.Is(err, jwks.ErrDiscoveryFailed) ||
+			errors.Is(err, jwks.ErrPrivateKeyMaterial) ||
+			errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, status.Error(codes.Unavailable, "keys unavailable")
+		}
+		return nil, status.Error(codes.Unauthenticated, describe(err))
+	}
+
+	return jwks.ContextWithToken(ctx, token), nil
+}
+
+func describe(err error) string {
+	return strings.TrimPrefix(err.Error(), "jwks: ")
+}
+
+// serverStream overrides context of wrapped stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
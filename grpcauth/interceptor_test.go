@@ -0,0 +1,209 @@
+package grpcauth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/danikarik/jwks/grpcauth"
+	"github.com/rakutentech/jwk-go/jwk"
+	"github.com/rakutentech/jwk-go/okp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// jwksHandler serves Ed25519 public key with kid `ed`.
+func jwksHandler(t *testing.T, pub ed25519.PublicKey) http.Handler {
+	data, err := json.Marshal(jwk.KeySpecSet{
+		Keys: []jwk.KeySpec{*jwk.NewSpecWithID("ed", okp.NewEd25519(pub, nil))},
+	})
+	require.NoError(t, err)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
+
+func signToken(t *testing.T, key ed25519.PrivateKey) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := encode(map[string]string{"alg": "EdDSA", "kid": "ed"}) + "." + encode(map[string]string{"sub": "user"})
+	signature := ed25519.Sign(key, []byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
+
+func TestInterceptors(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(jwksHandler(t, pub))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	require.NoError(t, err)
+
+	verifier := jwks.NewVerifier(manager)
+
+	valid := signToken(t, priv)
+	invalid := signToken(t, other)
+
+	testCases := []struct {
+		Name     string
+		Options  []grpcauth.Option
+		Metadata metadata.MD
+		Method   string
+		Code     codes.Code
+	}{
+		{
+			Name:     "OK",
+			Metadata: metadata.Pairs("authorization", "Bearer "+valid),
+			Code:     codes.OK,
+		},
+		{
+			Name:     "CustomKey",
+			Options:  []grpcauth.Option{grpcauth.WithMetadataKey("x-token")},
+			Metadata: metadata.Pairs("x-token", valid),
+			Code:     codes.OK,
+		},
+		{
+			Name:     "Missing",
+			Metadata: metadata.MD{},
+			Code:     codes.Unauthenticated,
+		},
+		{
+			Name:     "Scheme",
+			Metadata: metadata.Pairs("authorization", "Basic dXNlcjpwYXNz"),
+			Code:     codes.Unauthenticated,
+		},
+		{
+			Name:     "Invalid",
+			Metadata: metadata.Pairs("authorization", "Bearer "+invalid),
+			Code:     codes.Unauthenticated,
+		},
+		{
+			Name:     "Skipped",
+			Options:  []grpcauth.Option{grpcauth.WithSkipMethods("/grpc.health.v1.Health/Check")},
+			Metadata: metadata.MD{},
+			Method:   "/grpc.health.v1.Health/Check",
+			Code:     codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			ctx := metadata.NewIncomingContext(context.Background(), tc.Metadata)
+			authenticated := tc.Method == ""
+
+			check := func(ctx context.Context) error {
+				claims, ok := jwks.ClaimsFromContext(ctx)
+				r.Equal(authenticated, ok)
+				if ok {
+					r.Equal("user", claims["sub"])
+				}
+				return nil
+			}
+
+			unary := grpcauth.UnaryServerInterceptor(verifier, tc.Options...)
+			_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.Method},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, check(ctx)
+				},
+			)
+			r.Equal(tc.Code, status.Code(err))
+
+			stream := grpcauth.StreamServerInterceptor(verifier, tc.Options...)
+			err = stream(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: tc.Method},
+				func(srv interface{}, ss grpc.ServerStream) error {
+					return check(ss.Context())
+				},
+			)
+			r.Equal(tc.Code, status.Code(err))
+		})
+	}
+}
+
+func TestInterceptorsKeysUnavailable(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// Symmetric key is private key material.
+	leaked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keys":[{"kty":"oct","kid":"ed","k":"c2VjcmV0"}]}`))
+	}))
+	defer leaked.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithMaxRetries(1),
+	)
+	require.NoError(t, err)
+
+	rejecting, err := jwks.NewManager(leaked.URL,
+		jwks.WithPrivateKeyPolicy(jwks.RejectKeySet),
+	)
+	require.NoError(t, err)
+
+	md := metadata.Pairs("authorization", "Bearer "+signToken(t, priv))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		Name    string
+		Ctx     context.Context
+		Manager jwks.Manager
+	}{
+		{Name: "ConnectionFailed", Ctx: context.Background(), Manager: manager},
+		{Name: "Canceled", Ctx: canceled, Manager: manager},
+		{Name: "PrivateKeyMaterial", Ctx: context.Background(), Manager: rejecting},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(tc.Ctx, md)
+
+			unary := grpcauth.UnaryServerInterceptor(jwks.NewVerifier(tc.Manager))
+			_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, nil
+				},
+			)
+
+			st := status.Convert(err)
+			require.Equal(t, codes.Unavailable, st.Code())
+			require.Equal(t, "keys unavailable", st.Message())
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package jwks
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validators holds HTTP caching metadata of the last fetched key set.
+type validators struct {
+	url          string
+	etag         string
+	lastModified string
+	// expires is a moment when key set becomes stale.
+	// Zero value means that source did not define freshness lifetime.
+	expires time.Time
+	// updated is a moment when response defining expiration has been received.
+	updated time.Time
+}
+
+// stale reports whether key set has to be revalidated.
+func (v validators) stale(now time.Time) bool {
+	return !v.expires.IsZero() && !now.Before(v.expires)
+}
+
+// apply sets conditional headers on request.
+func (v validators) apply(req *http.Request) {
+	if v.etag != "" {
+		req.Header.Set("If-None-Match", v.etag)
+	}
+	if v.lastModified != "" {
+		req.Header.Set("If-Modified-Since", v.lastModified)
+	}
+}
+
+// update returns validators refreshed with response headers.
+// Values missing in `304 Not Modified` responses are kept, including
+// freshness lifetime (RFC 9111 Section 4.3.4).
+func (v validators) update(h http.Header, now time.Time) validators {
+	if etag := h.Get("ETag"); etag != "" {
+		v.etag = etag
+	}
+	if lastModified := h.Get("Last-Modified"); lastModified != "" {
+		v.lastModified = lastModified
+	}
+	if expires := expiresAt(h, now); !expires.IsZero() || v.expires.IsZero() {
+		v.expires = expires
+	} else {
+		v.expires = now.Add(v.expires.Sub(v.updated))
+	}
+	v.updated = now
+	return v
+}
+
+// expiresAt calculates freshness lifetime of response
+// using `Cache-Control`, `Age`, `Date` and `Expires` headers.
+func expiresAt(h http.Header, now time.Time) time.Time {
+	if cc := h.Get("Cache-Control"); cc != "" {
+		directives := parseCacheControl(cc)
+
+		if _, ok := directives["no-store"]; ok {
+			return now
+		}
+		if _, ok := directives["no-cache"]; ok {
+			return now
+		}
+
+		if v, ok := directives["max-age"]; ok {
+			maxAge, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || maxAge < 0 {
+				return now
+			}
+
+			lifetime := time.Duration(maxAge) * time.Second
+			if age, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && age > 0 {
+				lifetime -= time.Duration(age) * time.Second
+			}
+
+			return now.Add(lifetime)
+		}
+	}
+
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			// Invalid dates such as "0" represent a time in the past.
+			return now
+		}
+
+		// Prefer server clock to avoid local clock skew.
+		if date, err := http.ParseTime(h.Get("Date")); err == nil {
+			return now.Add(expires.Sub(date))
+		}
+
+		return expires
+	}
+
+	return time.Time{}
+}
+
+func parseCacheControl(v string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value := part, ""
+		if i := strings.Index(part, "="); i >= 0 {
+			name, value = part[:i], strings.Trim(part[i+1:], `"`)
+		}
+
+		directives[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return directives
+}
@@ -0,0 +1,110 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+
+	"github.com/rakutentech/jwk-go/okp"
+)
+
+// ErrUnsupportedKey raises when JWK cannot be converted into public key.
+var ErrUnsupportedKey = errors.New("jwks: unsupported key type")
+
+// parsedKey holds public key converted from JWK.
+type parsedKey struct {
+	jwk *JWK
+	pub crypto.PublicKey
+}
+
+// publicKey converts JWK into `*rsa.PublicKey`, `*ecdsa.PublicKey` or `ed25519.PublicKey`.
+func publicKey(key *JWK) (crypto.PublicKey, error) {
+	spec, err := key.ParseKeySpec()
+	if err != nil {
+		return nil, err
+	}
+
+	return cryptoKey(spec.Key)
+}
+
+// cryptoKey returns public part of key parsed by `jwk` package.
+func cryptoKey(key interface{}) (crypto.PublicKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PublicKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	case okp.CurveOctetKeyPair:
+		if k.Curve() == "Ed25519" {
+			return ed25519.PublicKey(k.PublicKey()), nil
+		}
+	}
+
+	return nil, ErrUnsupportedKey
+}
+
+// FetchPublicKey returns parsed public key by kid. Conversion result is
+// cached, so JWK is parsed only once.
+func (m *manager) FetchPublicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	_, pub, err := fetchKeyPair(ctx, m, kid)
+	return pub, err
+}
+
+// keyParser is implemented by managers which cache parsed public keys.
+type keyParser interface {
+	publicKeyOf(key *JWK) (crypto.PublicKey, error)
+}
+
+// publicKeyOf returns public part of fetched key.
+func (m *manager) publicKeyOf(key *JWK) (crypto.PublicKey, error) {
+	if key.Kid != "" {
+		if v, ok := m.parsed.Get(key.Kid); ok {
+			if pk := v.(parsedKey); pk.jwk == key {
+				return pk.pub, nil
+			}
+		}
+	}
+
+	// Key has been replaced in cache or is served by external cache.
+	pub, err := publicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Kid != "" {
+		m.parsed.Add(key.Kid, parsedKey{jwk: key, pub: pub})
+	}
+
+	return pub, nil
+}
+
+// parsePublicKey returns public part of key fetched by m.
+func parsePublicKey(m Manager, key *JWK) (crypto.PublicKey, error) {
+	if p, ok := m.(keyParser); ok {
+		return p.publicKeyOf(key)
+	}
+	return publicKey(key)
+}
+
+// fetchKeyPair returns key by kid together with its public part using
+// single lookup, so both come from the same key set.
+func fetchKeyPair(ctx context.Context, m Manager, kid string) (*JWK, crypto.PublicKey, error) {
+	key, err := m.FetchKey(ctx, kid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pub, err := parsePublicKey(m, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, pub, nil
+}
@@ -0,0 +1,233 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rakutentech/jwk-go/jwk"
+)
+
+// rawSet is a key set which keys are decoded one by one.
+type rawSet struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+// FetchReport describes result of key set download.
+type FetchReport struct {
+	// URL is a location of key set.
+	URL string
+	// Keys is a number of accepted keys.
+	Keys int
+	// Skipped lists keys excluded from set.
+	Skipped []SkippedKey
+	// Leaked lists keys published with private or symmetric material.
+	Leaked []LeakedKey
+}
+
+// SkippedKey describes key excluded from set.
+type SkippedKey struct {
+	// Index is a position of key in set.
+	Index int
+	Kid   string
+	Kty   string
+	Err   error
+}
+
+// FetchHook is called after every downloaded key set.
+type FetchHook func(report FetchReport)
+
+// parseKey decodes single key of set.
+func parseKey(raw json.RawMessage) (*jwk.KeySpec, keyParams, error) {
+	var params keyParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, params, err
+	}
+
+	spec, err := jwk.ParseBytes(raw)
+	if err != nil {
+		return nil, params, err
+	}
+
+	return spec, params, nil
+}
+
+func (m *manager) skipKey(report *FetchReport, i int, raw json.RawMessage, err error) {
+	skipped := SkippedKey{Index: i, Err: err}
+
+	// Identify key as much as possible.
+	var id struct{ Kid, Kty string }
+	if json.Unmarshal(raw, &id) == nil {
+		skipped.Kid, skipped.Kty = id.Kid, id.Kty
+	}
+
+	m.logger.Warn().Msgf("skipping key %d (kid %q, kty %q) with %v", i, skipped.Kid, skipped.Kty, err)
+	report.Skipped = append(report.Skipped, skipped)
+}
+
+// keyParams holds JWK members which are not present in `JWK` type.
+type keyParams struct {
+	KeyOps  []string `json:"key_ops,omitempty"`
+	X5t     string   `json:"x5t,omitempty"`
+	X5tS256 string   `json:"x5t#S256,omitempty"`
+	X5c     []string `json:"x5c,omitempty"`
+	// Non-standard members published by some providers.
+	Nbf float64 `json:"nbf,omitempty"`
+	Exp float64 `json:"exp,omitempty"`
+}
+
+// keyEntry is a key of last fetched set.
+type keyEntry struct {
+	key         *JWK
+	params      keyParams
+	thumbprints map[crypto.Hash]string
+	cert        *x509.Certificate
+	valid       validity
+}
+
+// KeyCriteria is used for selecting keys without kid.
+// Empty fields match any value. Keys without `alg`, `use` or `key_ops`
+// members match any requested value of the member.
+type KeyCriteria struct {
+	Alg    string
+	Kty    string
+	Use    string
+	Crv    string
+	KeyOps []string
+}
+
+// missKey identifies criteria in negative cache. Zero byte prefix
+// separates it from kids.
+func (c KeyCriteria) missKey() string {
+	return "\x00" + strings.Join([]string{c.Alg, c.Kty, c.Use, c.Crv, strings.Join(c.KeyOps, ",")}, "\x00")
+}
+
+func (c KeyCriteria) match(e keyEntry) bool {
+	key := e.key
+
+	if c.Kty != "" && key.Kty != c.Kty {
+		return false
+	}
+	if c.Crv != "" && key.Crv != c.Crv {
+		return false
+	}
+	if c.Alg != "" && key.Alg != "" && key.Alg != c.Alg {
+		return false
+	}
+	if c.Use != "" && key.Use != "" && key.Use != c.Use {
+		return false
+	}
+
+	if len(e.params.KeyOps) > 0 {
+		for _, op := range c.KeyOps {
+			if !containsAny(e.params.KeyOps, op) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// FetchKeys returns every key of set matching criteria in order of
+// their appearance in set. Keys published without kid are returned
+// with kid set to their base64url encoded RFC 7638 thumbprint.
+func (m *manager) FetchKeys(ctx context.Context, c KeyCriteria) ([]*JWK, error) {
+	entries := m.getEntries()
+
+	var stale []*JWK
+	if m.lookup {
+		if keys := matchKeys(entries, c); len(keys) > 0 {
+			if !m.revalidate(time.Now()) {
+				return keys, nil
+			}
+			stale = keys
+		}
+	}
+
+	if len(stale) == 0 {
+		if m.missed(c.missKey()) {
+			m.logger.Debug().Msgf("criteria %+v has recently been missed", c)
+			return nil, ErrPublicKeyNotFound
+		}
+
+		if m.throttled(time.Now()) {
+			m.logger.Debug().Msg("jwks refresh for criteria lookup is rate limited")
+			return nil, ErrPublicKeyNotFound
+		}
+	}
+
+	if _, err := m.fetchSet(ctx, m.lookup && len(entries) > 0); err != nil {
+		if len(stale) > 0 && !errors.Is(err, ErrPrivateKeyMaterial) {
+			m.logger.Debug().Msgf("revalidation failed with %v, serving stale keys", err)
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	keys := matchKeys(m.getEntries(), c)
+	if len(keys) == 0 {
+		m.rememberMiss(c.missKey())
+		return nil, ErrPublicKeyNotFound
+	}
+
+	return keys, nil
+}
+
+// matchKeys returns keys matching criteria which may be used at the moment.
+func matchKeys(entries []keyEntry, c KeyCriteria) []*JWK {
+	now := time.Now()
+
+	var keys []*JWK
+	for _, e := range entries {
+		if c.match(e) && e.valid.contains(now) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+func (m *manager) getEntries() []keyEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.entries
+}
+
+// setEntries saves entries of fetched set. Keys missing from it are dropped
+// from cache, so removed or rotated keys are not served anymore.
+func (m *manager) setEntries(ctx context.Context, entries []keyEntry) {
+	index := buildIndex(entries)
+
+	windows := make(map[string]validity)
+	for _, e := range entries {
+		if !e.valid.isZero() && e.key.Kid != "" {
+			windows[e.key.Kid] = e.valid
+		}
+	}
+
+	m.mu.Lock()
+	prev := m.entries
+	m.entries = entries
+	m.index = index
+	m.windows = windows
+	m.mu.Unlock()
+
+	kids := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		kids[e.key.Kid] = true
+	}
+
+	for _, e := range prev {
+		if kid := e.key.Kid; kid != "" && !kids[kid] {
+			m.logger.Debug().Msgf("removing %s missing from key set", kid)
+			if err := m.cache.Remove(ctx, kid); err != nil {
+				m.logger.Debug().Msgf("failed cache removal of %s with %v", kid, err)
+			}
+			m.parsed.Remove(kid)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package jwks_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/rakutentech/jwk-go/jwk"
+	"github.com/stretchr/testify/require"
+)
+
+type rawKey struct {
+	Key    interface{}
+	Params map[string]interface{}
+}
+
+// rawJWKSHandler serves keys with members which are not supported by `jwk` package.
+func rawJWKSHandler(t *testing.T, keys ...rawKey) http.Handler {
+	var set []map[string]interface{}
+
+	for _, k := range keys {
+		data, err := json.Marshal(jwk.NewSpec(k.Key))
+		require.NoError(t, err)
+
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &m))
+
+		for name, v := range k.Params {
+			m[name] = v
+		}
+
+		set = append(set, m)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"keys": set})
+	require.NoError(t, err)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
+
+func TestManagerFetchKeys(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(rawJWKSHandler(t,
+		rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa-enc", "use": "enc"}},
+		rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa-sig", "alg": "RS256", "key_ops": []string{"verify"}}},
+		rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec"}},
+		rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa-any"}},
+	))
+	defer ts.Close()
+
+	testCases := []struct {
+		Name     string
+		Criteria jwks.KeyCriteria
+		Kids     []string
+		Error    error
+	}{
+		{
+			Name:     "Signature",
+			Criteria: jwks.KeyCriteria{Alg: "RS256", Kty: "RSA", Use: "sig"},
+			Kids:     []string{"rsa-sig", "rsa-any"},
+		},
+		{
+			Name:     "Algorithm",
+			Criteria: jwks.KeyCriteria{Alg: "PS256", Kty: "RSA"},
+			Kids:     []string{"rsa-enc", "rsa-any"},
+		},
+		{
+			Name:     "Curve",
+			Criteria: jwks.KeyCriteria{Kty: "EC", Crv: "P-256"},
+			Kids:     []string{"ec"},
+		},
+		{
+			Name:     "KeyOps",
+			Criteria: jwks.KeyCriteria{Kty: "RSA", KeyOps: []string{"verify"}},
+			Kids:     []string{"rsa-enc", "rsa-sig", "rsa-any"},
+		},
+		{
+			Name:     "NotFound",
+			Criteria: jwks.KeyCriteria{Kty: "OKP"},
+			Error:    jwks.ErrPublicKeyNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			manager, err := jwks.NewManager(ts.URL)
+			r.NoError(err)
+
+			found, err := manager.FetchKeys(context.Background(), tc.Criteria)
+			if tc.Error != nil {
+				r.ErrorIs(err, tc.Error)
+				return
+			}
+
+			r.NoError(err)
+
+			var kids []string
+			for _, key := range found {
+				kids = append(kids, key.Kid)
+			}
+			r.Equal(tc.Kids, kids)
+		})
+	}
+}
+
+func TestManagerFetchKeysNegativeCache(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	handler := rawJWKSHandler(t, rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa"}})
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL, jwks.WithNegativeCacheTTL(time.Minute))
+	r.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		_, err = manager.FetchKeys(context.Background(), jwks.KeyCriteria{Kty: "OKP"})
+		r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+	}
+	r.Equal(int32(1), atomic.LoadInt32(&requests))
+
+	// Other criteria are not affected.
+	found, err := manager.FetchKeys(context.Background(), jwks.KeyCriteria{Kty: "RSA"})
+	r.NoError(err)
+	r.Len(found, 1)
+}
+
+func TestVerifierWithoutKid(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+	other := newSigningKeys(t)
+
+	ts := httptest.NewServer(jwksHandler(
+		testKey{"other", &other.RSA.PublicKey},
+		testKey{"rsa", &keys.RSA.PublicKey},
+		testKey{"ec", &keys.ECDSA.PublicKey},
+	))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	verifier := jwks.NewVerifier(manager)
+	claims := map[string]interface{}{"sub": "user"}
+
+	token, err := verifier.Verify(context.Background(), signToken(t, map[string]interface{}{"alg": "RS256"}, claims, keys.RSA))
+	r.NoError(err)
+	r.Equal("rsa", token.Key.Kid)
+
+	_, err = verifier.Verify(context.Background(), signToken(t, map[string]interface{}{"alg": "RS256"}, claims, newSigningKeys(t).RSA))
+	r.ErrorIs(err, jwks.ErrInvalidSignature)
+
+	_, err = verifier.Verify(context.Background(), signToken(t, map[string]interface{}{"alg": "EdDSA"}, claims, keys.Ed25519))
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+}
+
+func TestManagerKeyWithoutKid(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(rawJWKSHandler(t,
+		rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa"}},
+		rawKey{&keys.ECDSA.PublicKey, nil},
+	))
+	defer ts.Close()
+
+	tp, err := jwk.NewSpec(&keys.ECDSA.PublicKey).Thumbprint()
+	r.NoError(err)
+	thumbprint := base64.RawURLEncoding.EncodeToString(tp)
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	key, err := manager.FetchKey(ctx, "rsa")
+	r.NoError(err)
+	r.Equal("rsa", key.Kid)
+
+	found, err := manager.FetchKeys(ctx, jwks.KeyCriteria{Kty: "EC"})
+	r.NoError(err)
+	r.Len(found, 1)
+	r.Equal(thumbprint, found[0].Kid)
+
+	key, err = manager.FetchKey(ctx, thumbprint)
+	r.NoError(err)
+	r.Equal("EC", key.Kty)
+
+	size, err := manager.CacheSize(ctx)
+	r.NoError(err)
+	r.Equal(2, size)
+
+	token, err := jwks.NewVerifier(manager).Verify(ctx,
+		signToken(t, map[string]interface{}{"alg": "ES256"}, map[string]interface{}{"sub": "user"}, keys.ECDSA),
+	)
+	r.NoError(err)
+	r.Equal(thumbprint, token.Key.Kid)
+}
+
+func TestManagerSkipsInvalidKeys(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	good, err := json.Marshal(jwk.NewSpec(&keys.RSA.PublicKey))
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(good, &m))
+	m["kid"] = "rsa"
+	good, err = json.Marshal(m)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name    string
+		Keys    []string
+		Accept  int
+		Skipped []string
+		Error   error
+	}{
+		{
+			Name:    "UnknownKeyType",
+			Keys:    []string{`{"kty":"XYZ","kid":"unknown"}`, string(good)},
+			Accept:  1,
+			Skipped: []string{"unknown"},
+		},
+		{
+			Name:    "MalformedKey",
+			Keys:    []string{string(good), `{"kty":"RSA","kid":"broken","n":"!!!","e":"AQAB"}`},
+			Accept:  1,
+			Skipped: []string{"broken"},
+		},
+		{
+			Name:    "NoValidKeys",
+			Keys:    []string{`{"kty":"XYZ","kid":"unknown"}`, `{"kty":"EC","kid":"ec","crv":"P-256"}`},
+			Skipped: []string{"unknown", "ec"},
+			Error:   jwks.ErrPublicKeyNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			body := `{"keys":[` + strings.Join(tc.Keys, ",") + `]}`
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(body))
+			}))
+			defer ts.Close()
+
+			var reports []jwks.FetchReport
+			hook := func(r jwks.FetchReport) { reports = append(reports, r) }
+
+			mng, err := jwks.NewManager(ts.URL, jwks.WithFetchHook(hook))
+			require.NoError(t, err)
+
+			key, err := mng.FetchKey(context.Background(), "rsa")
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "rsa", key.Kid)
+			}
+
+			require.Len(t, reports, 1)
+			require.Equal(t, ts.URL, reports[0].URL)
+			require.Equal(t, tc.Accept, reports[0].Keys)
+
+			var skipped []string
+			for _, s := range reports[0].Skipped {
+				require.Error(t, s.Err)
+				skipped = append(skipped, s.Kid)
+			}
+			require.Equal(t, tc.Skipped, skipped)
+		})
+	}
+}
@@ -2,23 +2,27 @@ package jwks
 
 import (
 	"context"
-	"encoding/json"
+	"crypto"
+	"crypto/x509"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/rakutentech/jwk-go/jwk"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/singleflight"
 )
 
 const (
-	_defaultRetries   = 5
-	_defaultCacheSize = 100
-	_defaultTimeout   = 5 * time.Second
+	_defaultRetries      = 5
+	_defaultCacheSize    = 100
+	_defaultTimeout      = 5 * time.Second
+	_defaultFetchTimeout = 30 * time.Second
 )
 
 // JWK represents an unparsed JSON Web Key (JWK) in its wire format.
@@ -33,15 +37,27 @@ var (
 	ErrKeyIDNotProvided = errors.New("jwks: kid is not provided")
 	// ErrPublicKeyNotFound raises when no public key is found.
 	ErrPublicKeyNotFound = errors.New("jwks: public key not found")
+	// ErrLookupDisabled raises when background refresh is started without cache lookup.
+	ErrLookupDisabled = errors.New("jwks: cache lookup is disabled")
+	// ErrRefresherStarted raises when background refresh is already running.
+	ErrRefresherStarted = errors.New("jwks: background refresh is already started")
 )
 
 // Manager fetches and returns JWK from public source.
 type Manager interface {
 	FetchKey(ctx context.Context, kid string) (*JWK, error)
+	FetchPublicKey(ctx context.Context, kid string) (crypto.PublicKey, error)
+	FetchKeys(ctx context.Context, c KeyCriteria) ([]*JWK, error)
+	FetchKeyByThumbprint(ctx context.Context, thumbprint string, hash crypto.Hash) (*JWK, error)
+	FetchKeyByX5t(ctx context.Context, x5t string) (*JWK, error)
+	Certificate(ctx context.Context, kid string) (*x509.Certificate, error)
 	CacheSize(ctx context.Context) (int, error)
+	Start(ctx context.Context) error
+	Close() error
 }
 
 type manager struct {
+	source  string
 	url     *url.URL
 	cache   Cache
 	client  *http.Client
@@ -49,6 +65,36 @@ type manager struct {
 	retries int
 	logger  zerolog.Logger
 	group   singleflight.Group
+
+	retryPolicy RetryPolicy
+	fetchHook   FetchHook
+	x5cOptions  *x509.VerifyOptions
+	keyPolicy   KeyPolicy
+
+	privateKeyPolicy PrivateKeyPolicy
+
+	refreshInterval time.Duration
+	fetchTimeout    time.Duration
+	attemptTimeout  time.Duration
+	maxBodySize     int64
+
+	discovery         *discovery
+	discoveryInterval time.Duration
+
+	missTTL            time.Duration
+	misses             *lru.Cache
+	parsed             *lru.Cache
+	minRefreshInterval time.Duration
+
+	mu          sync.RWMutex
+	validators  validators
+	entries     []keyEntry
+	index       map[string]*JWK
+	windows     map[string]validity
+	lastRefresh time.Time
+	cancel      context.CancelFunc
+	done        chan struct{}
+	session     *session
 }
 
 // NewManager returns a new instance of `Manager`.
@@ -58,7 +104,16 @@ func NewManager(rawurl string, opts ...Option) (Manager, error) {
 		return nil, ErrInvalidURL
 	}
 
+	mng := newManager(rawurl, opts...)
+	mng.url = url
+
+	return mng, nil
+}
+
+func newManager(source string, opts ...Option) *manager {
 	cache, _ := NewLRUCache(_defaultCacheSize)
+	misses, _ := lru.New(_defaultMissCacheSize)
+	parsed, _ := lru.New(_defaultCacheSize)
 
 	logger := zerolog.
 		New(os.Stderr).With().
@@ -66,19 +121,29 @@ func NewManager(rawurl string, opts ...Option) (Manager, error) {
 		Level(zerolog.Disabled)
 
 	mng := &manager{
-		url:     url,
+		source:  source,
 		cache:   cache,
 		client:  &http.Client{Timeout: _defaultTimeout},
 		lookup:  true,
 		retries: _defaultRetries,
 		logger:  logger,
+
+		retryPolicy:       ExponentialBackoff(_defaultBackoffBase, _defaultBackoffMax),
+		refreshInterval:   _defaultRefreshInterval,
+		fetchTimeout:      _defaultFetchTimeout,
+		attemptTimeout:    _defaultTimeout,
+		maxBodySize:       _defaultMaxBodySize,
+		discoveryInterval: _defaultDiscoveryInterval,
+		misses:            misses,
+		parsed:            parsed,
+		session:           newSession(),
 	}
 
 	for _, opt := range opts {
 		opt(mng)
 	}
 
-	return mng, nil
+	return mng
 }
 
 func (m *manager) FetchKey(ctx context.Context, kid string) (*JWK, error) {
@@ -91,100 +156,381 @@ func (m *manager) FetchKey(ctx context.Context, kid string) (*JWK, error) {
 		m.logger.Debug().Msgf("lookup cache for %s", kid)
 
 		key, err := m.cache.Get(ctx, kid)
+		if err == nil && !m.valid(kid, time.Now()) {
+			// Key has expired or is not valid yet, so it might have been rotated.
+			m.logger.Debug().Msgf("%s is outside of its validity window, refetching jwks", kid)
+
+			if m.missed(expiredKey(kid)) || m.throttled(time.Now()) {
+				return nil, ErrKeyExpired
+			}
+
+			key, err := m.checkValidity(m.fetchKey(ctx, kid, false))
+			if errors.Is(err, ErrKeyExpired) {
+				m.rememberMiss(expiredKey(kid))
+			}
+
+			return key, err
+		}
 		if err == nil {
-			return key, nil
+			if !m.revalidate(time.Now()) {
+				return key, nil
+			}
+
+			// Key set has expired according to source cache headers.
+			m.logger.Debug().Msgf("revalidating stale key set for %s", kid)
+
+			fresh, err := m.fetchKey(ctx, kid, true)
+			if err != nil && !errors.Is(err, ErrPublicKeyNotFound) && !errors.Is(err, ErrPrivateKeyMaterial) {
+				m.logger.Debug().Msgf("revalidation failed with %v, serving stale %s", err, kid)
+				return key, nil
+			}
+
+			return m.checkValidity(fresh, err)
 		}
 	}
 
+	if m.missed(kid) {
+		m.logger.Debug().Msgf("%s has recently been missed", kid)
+		return nil, ErrPublicKeyNotFound
+	}
+
+	if m.throttled(time.Now()) {
+		m.logger.Debug().Msgf("jwks refresh for %s is rate limited", kid)
+		return nil, ErrPublicKeyNotFound
+	}
+
 	// Otherwise fetch from public JWKS.
-	v, err, _ := m.group.Do(kid, func() (interface{}, error) {
-		return m.fetchKey(ctx, kid)
+	key, err := m.fetchKey(ctx, kid, false)
+	if errors.Is(err, ErrPublicKeyNotFound) {
+		m.rememberMiss(kid)
+	}
+
+	return m.checkValidity(key, err)
+}
+
+// fetchSet downloads key set once for all concurrent callers
+// regardless of kid they are looking for. Download runs under manager
+// session, so each caller waits only as long as its context allows.
+func (m *manager) fetchSet(ctx context.Context, revalidate bool) ([]*JWK, error) {
+	ch := m.group.DoChan(m.source, func() (interface{}, error) {
+		s := m.join()
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(s.ctx, m.fetchTimeout)
+		defer cancel()
+
+		return m.refresh(ctx, revalidate)
 	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		return res.Val.([]*JWK), nil
+	}
+}
+
+// jwksURL returns location of key set.
+func (m *manager) jwksURL(ctx context.Context) (string, error) {
+	if m.discovery != nil {
+		return m.discovery.resolve(ctx, m)
+	}
+	return m.url.String(), nil
+}
+
+func (m *manager) getValidators() validators {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.validators
+}
+
+func (m *manager) setValidators(v validators) {
+	m.mu.Lock()
+	m.validators = v
+	m.mu.Unlock()
+}
+
+// fetchKey downloads key set and returns key by given kid.
+// If revalidate is true, request is sent with conditional headers
+// because the key is already in cache.
+func (m *manager) fetchKey(ctx context.Context, kid string, revalidate bool) (*JWK, error) {
+	keys, err := m.fetchSet(ctx, revalidate)
 	if err != nil {
 		return nil, err
 	}
 
-	return v.(*JWK), nil
+	if keys == nil {
+		key, err := m.cache.Get(ctx, kid)
+		if err == nil {
+			return key, nil
+		}
+
+		// Key might have been evicted from cache, so download the whole set again.
+		m.logger.Debug().Msgf("%s is missing in cache, refetching jwks", kid)
+		return m.fetchKey(ctx, kid, false)
+	}
+
+	for _, key := range keys {
+		if key.Kid == kid {
+			return key, nil
+		}
+	}
+
+	return nil, ErrPublicKeyNotFound
 }
 
-func (m *manager) fetchKey(ctx context.Context, kid string) (*JWK, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.url.String(), nil)
+// refresh downloads key set from source and saves it into cache.
+// Returned keys are nil if source responded with `304 Not Modified`.
+func (m *manager) refresh(ctx context.Context, revalidate bool) ([]*JWK, error) {
+	m.touchRefresh(time.Now())
+
+	start := time.Now()
+
+	u, err := m.jwksURL(ctx)
 	if err != nil {
-		return nil, err
+		// Discovery describes its failed metadata request, otherwise
+		// location of key set is unknown, so source is reported instead.
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) {
+			fetchErr = &FetchError{URL: m.source, Err: err}
+		}
+		fetchErr.Elapsed = time.Since(start)
+		return nil, fetchErr
 	}
 
-	var set jwk.KeySpecSet
+	// Validators are meaningless if jwks uri has changed since last fetch.
+	var conditional *validators
+	current := m.getValidators()
+	if revalidate && current.url == u {
+		conditional = &current
+	}
 
-	// Make sure that you have exponential back off on this http request with retries.
-	retries := m.retries
-	for retries > 0 {
-		retries--
+	var (
+		set      rawSet
+		resp     *http.Response
+		fetchErr = &FetchError{URL: u}
+	)
 
-		m.logger.Debug().Msgf("fetching %s from jwks source", kid)
-		resp, err := m.client.Do(req)
-		if err != nil {
-			m.logger.Debug().Msgf("request failed with error %v", err)
-			continue
+	failed := func(cause error) ([]*JWK, error) {
+		fetchErr.Err = cause
+		fetchErr.Elapsed = time.Since(start)
+		return nil, fetchErr
+	}
+
+	backoff := m.retryPolicy()
+
+	attempts := m.retries
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for {
+		m.logger.Debug().Msgf("fetching jwks from %s", u)
+		fetchErr.Attempts++
+
+		var retry bool
+		set = rawSet{}
+		resp, retry, err = m.attempt(ctx, u, conditional, &set)
+		if resp != nil {
+			fetchErr.StatusCode = resp.StatusCode
+		}
+		if err == nil {
+			break
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			m.logger.Debug().Msgf("request failed with %d status code", resp.StatusCode)
-			continue
+		m.logger.Debug().Msgf("request failed with %v", err)
+
+		if !retry {
+			return failed(err)
 		}
 
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			m.logger.Debug().Msgf("response body reading failed with %v", err)
-			continue
+		if fetchErr.Attempts >= attempts {
+			m.logger.Debug().Msgf("max retries exceeded for %s", u)
+			return failed(err)
 		}
 
-		if err := json.Unmarshal(data, &set); err != nil {
-			m.logger.Debug().Msgf("response body encoding failed with %v", err)
-			return nil, err
+		delay, ok := backoff.Next()
+		if !ok {
+			m.logger.Debug().Msgf("retry policy exhausted for %s", u)
+			return failed(err)
 		}
 
-		break
+		// Server asked to wait longer, there is no point in
+		// waiting if attempt cannot be made in time.
+		if resp != nil {
+			if wait := retryAfter(resp.Header, time.Now()); wait > delay {
+				delay = wait
+				if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+					m.logger.Debug().Msgf("retry after %s exceeds deadline", delay)
+					return failed(err)
+				}
+			}
+		}
+
+		m.logger.Debug().Msgf("next attempt in %s", delay)
+		if err := sleep(ctx, delay); err != nil {
+			return failed(err)
+		}
 	}
 
-	if retries == 0 {
-		m.logger.Debug().Msgf("max retries exceeded for %s", m.url.String())
-		return nil, ErrConnectionFailed
+	header := resp.Header
+
+	if resp.StatusCode == http.StatusNotModified {
+		// Current set is still valid, just extend its lifetime.
+		m.setValidators(current.update(header, time.Now()))
+		return nil, nil
 	}
 
-	if len(set.Keys) == 0 {
-		m.logger.Debug().Msg("JWKS has no keys")
-		return nil, ErrPublicKeyNotFound
+	keys := make([]*JWK, 0, len(set.Keys))
+	entries := make([]keyEntry, 0, len(set.Keys))
+	report := FetchReport{URL: u}
+
+	// Save new set into cache. Invalid keys are skipped,
+	// so that they cannot make other keys unreachable.
+	screened, err := m.screenPrivate(ctx, &report, set.Keys)
+	if err != nil {
+		m.forget(ctx)
+		if m.fetchHook != nil {
+			m.fetchHook(report)
+		}
+		return nil, err
 	}
 
-	var res *JWK
+	for i, raw := range screened {
+		if raw == nil {
+			continue
+		}
+
+		spec, params, err := parseKey(raw)
+		if err != nil {
+			m.skipKey(&report, i, raw, err)
+			continue
+		}
 
-	// Save new set into cache.
-	for _, spec := range set.Keys {
 		jwk, err := spec.ToJWK()
 		if err != nil {
-			return nil, err
+			m.skipKey(&report, i, raw, err)
+			continue
+		}
+
+		pub, err := cryptoKey(spec.Key)
+		if err != nil {
+			m.logger.Debug().Msgf("public key conversion failed with %v", err)
+		}
+
+		if err := m.keyPolicy.check(jwk, pub); err != nil {
+			m.skipKey(&report, i, raw, err)
+			continue
+		}
+
+		cert, err := m.certificate(params.X5c, pub)
+		if err != nil {
+			m.skipKey(&report, i, raw, err)
+			continue
 		}
 
-		if m.lookup {
+		thumbprints := make(map[crypto.Hash]string, len(indexedHashes))
+		for _, h := range indexedHashes {
+			tp, err := thumbprint(spec, h)
+			if err != nil {
+				m.logger.Debug().Msgf("thumbprint calculation failed with %v", err)
+				continue
+			}
+			thumbprints[h] = tp
+		}
+
+		// Key without kid is identified by its thumbprint.
+		if jwk.Kid == "" {
+			jwk.Kid = thumbprints[crypto.SHA256]
+		}
+
+		if m.lookup && jwk.Kid != "" {
 			m.logger.Debug().Msgf("saving %s into cache", jwk.Kid)
 
 			if err := m.cache.Add(ctx, jwk); err != nil {
 				m.logger.Debug().Msgf("failed cache save for %s with %v", jwk.Kid, err)
-				return nil, err
 			}
 		}
 
-		if jwk.Kid == kid {
-			res = jwk
+		// Parse public key once while specification is at hand.
+		if pub != nil && jwk.Kid != "" {
+			m.parsed.Add(jwk.Kid, parsedKey{jwk: jwk, pub: pub})
 		}
+
+		keys = append(keys, jwk)
+		entries = append(entries, keyEntry{
+			key:         jwk,
+			params:      params,
+			thumbprints: thumbprints,
+			cert:        cert,
+			valid:       keyValidity(params, cert),
+		})
 	}
 
-	if res == nil {
+	report.Keys = len(keys)
+	if m.fetchHook != nil {
+		m.fetchHook(report)
+	}
+
+	m.setEntries(ctx, entries)
+
+	if len(keys) == 0 {
+		m.logger.Debug().Msg("JWKS has no keys")
 		return nil, ErrPublicKeyNotFound
 	}
 
-	return res, nil
+	m.setValidators(validators{url: u}.update(header, time.Now()))
+
+	return keys, nil
+}
+
+// attempt sends single request for key set and decodes response into set.
+// It reports whether failed attempt may succeed if repeated.
+func (m *manager) attempt(ctx context.Context, u string, conditional *validators, set *rawSet) (*http.Response, bool, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, m.attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	if conditional != nil {
+		conditional.apply(req)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		// Next attempt cannot succeed if the whole download has timed out.
+		return nil, ctx.Err() == nil, err
+	}
+	defer drainBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotModified && conditional != nil {
+		m.logger.Debug().Msg("jwks has not been modified")
+		return resp, false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, retryable(resp.StatusCode), fmt.Errorf("jwks: unexpected status code %d", resp.StatusCode)
+	}
+
+	if err := checkContentType(resp.Header); err != nil {
+		m.logger.Debug().Msgf("unexpected content type %q", resp.Header.Get("Content-Type"))
+		return resp, false, err
+	}
+
+	if err := decodeBody(resp, m.maxBodySize, set); err != nil {
+		// Body might have been cut by attempt timeout.
+		return resp, attemptCtx.Err() != nil && ctx.Err() == nil, err
+	}
+
+	return resp, false, nil
 }
 
 func (m *manager) CacheSize(ctx context.Context) (int, error) {
@@ -2,15 +2,24 @@ package jwks_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/danikarik/jwks"
 	"github.com/rakutentech/jwk-go/jwk"
+	"github.com/rakutentech/jwk-go/okp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,7 +29,7 @@ type testKey struct {
 }
 
 func randomKeys() (*rsa.PrivateKey, *rsa.PublicKey, error) {
-	priv, err := rsa.GenerateKey(rand.Reader, 512)
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -150,3 +159,700 @@ func TestManagerCachedFetchKey(t *testing.T) {
 		})
 	}
 }
+
+func TestManagerRetryPolicy(t *testing.T) {
+	r := require.New(t)
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithMaxRetries(3),
+		jwks.WithRetryPolicy(jwks.ConstantBackoff(50*time.Millisecond)),
+	)
+	r.NoError(err)
+
+	start := time.Now()
+	_, err = manager.FetchKey(context.Background(), "202101")
+	r.ErrorIs(err, jwks.ErrConnectionFailed)
+	r.GreaterOrEqual(int64(time.Since(start)), int64(100*time.Millisecond))
+	r.Equal(int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestManagerRetryContextCancel(t *testing.T) {
+	r := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Minute)),
+	)
+	r.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = manager.FetchKey(ctx, "202101")
+	r.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func TestManagerRetryClassification(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		StatusCode int
+		Attempts   int32
+	}{
+		{Name: "NotFound", StatusCode: http.StatusNotFound, Attempts: 1},
+		{Name: "Unauthorized", StatusCode: http.StatusUnauthorized, Attempts: 1},
+		{Name: "Forbidden", StatusCode: http.StatusForbidden, Attempts: 1},
+		{Name: "RequestTimeout", StatusCode: http.StatusRequestTimeout, Attempts: 3},
+		{Name: "TooManyRequests", StatusCode: http.StatusTooManyRequests, Attempts: 3},
+		{Name: "InternalServerError", StatusCode: http.StatusInternalServerError, Attempts: 3},
+		{Name: "BadGateway", StatusCode: http.StatusBadGateway, Attempts: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var attempts int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				http.Error(w, http.StatusText(tc.StatusCode), tc.StatusCode)
+			}))
+			defer ts.Close()
+
+			manager, err := jwks.NewManager(ts.URL,
+				jwks.WithMaxRetries(3),
+				jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Millisecond)),
+			)
+			require.NoError(t, err)
+
+			_, err = manager.FetchKey(context.Background(), "202101")
+			require.ErrorIs(t, err, jwks.ErrConnectionFailed)
+
+			var fetchErr *jwks.FetchError
+			require.ErrorAs(t, err, &fetchErr)
+			require.Equal(t, tc.StatusCode, fetchErr.StatusCode)
+			require.Equal(t, tc.Attempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestManagerRetryAfter(t *testing.T) {
+	_, pub, err := randomKeys()
+	require.NoError(t, err)
+
+	handler := jwksHandler(testKey{Kid: "202101", Key: pub})
+
+	testCases := []struct {
+		Name       string
+		RetryAfter func() string
+		Timeout    time.Duration
+		MinElapsed time.Duration
+		Error      error
+	}{
+		{
+			Name:       "Seconds",
+			RetryAfter: func() string { return "1" },
+			Timeout:    5 * time.Second,
+			MinElapsed: time.Second,
+		},
+		{
+			Name: "Date",
+			RetryAfter: func() string {
+				// Date has second precision, so wait is at least one second.
+				return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+			},
+			Timeout:    5 * time.Second,
+			MinElapsed: time.Second,
+		},
+		{
+			Name:       "ExceedsDeadline",
+			RetryAfter: func() string { return "120" },
+			Timeout:    time.Second,
+			Error:      jwks.ErrConnectionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var attempts int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) == 1 {
+					w.Header().Set("Retry-After", tc.RetryAfter())
+					http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+					return
+				}
+				handler.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			manager, err := jwks.NewManager(ts.URL,
+				jwks.WithFetchTimeout(tc.Timeout),
+				jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Millisecond)),
+			)
+			require.NoError(t, err)
+
+			start := time.Now()
+			key, err := manager.FetchKey(context.Background(), "202101")
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+				require.Less(t, int64(time.Since(start)), int64(tc.Timeout))
+				require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "202101", key.Kid)
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(tc.MinElapsed))
+			require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+// flakyHandler fails first n requests with given status code.
+func flakyHandler(n int32, status int, next http.Handler) (http.Handler, *int32) {
+	var requests int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= n {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &requests
+}
+
+func TestManagerFlakyServer(t *testing.T) {
+	_, pub, err := randomKeys()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name     string
+		Failures int32
+		Attempts int32
+		Error    error
+	}{
+		{
+			Name:     "FirstAttempt",
+			Failures: 0,
+			Attempts: 1,
+		},
+		{
+			Name:     "SecondAttempt",
+			Failures: 1,
+			Attempts: 2,
+		},
+		{
+			Name:     "LastAttempt",
+			Failures: 2,
+			Attempts: 3,
+		},
+		{
+			Name:     "NoAttemptLeft",
+			Failures: 3,
+			Attempts: 3,
+			Error:    jwks.ErrConnectionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler, requests := flakyHandler(tc.Failures, http.StatusServiceUnavailable,
+				jwksHandler(testKey{Kid: "202101", Key: pub}))
+
+			var conns int32
+			ts := httptest.NewUnstartedServer(handler)
+			ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt32(&conns, 1)
+				}
+			}
+			ts.Start()
+			defer ts.Close()
+
+			manager, err := jwks.NewManager(ts.URL,
+				jwks.WithMaxRetries(3),
+				jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Millisecond)),
+			)
+			require.NoError(t, err)
+
+			key, err := manager.FetchKey(context.Background(), "202101")
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "202101", key.Kid)
+			}
+
+			require.Equal(t, tc.Attempts, atomic.LoadInt32(requests))
+			// Bodies of failed attempts are drained, so connection is reused.
+			require.Equal(t, int32(1), atomic.LoadInt32(&conns))
+		})
+	}
+}
+
+func TestManagerAttemptTimeout(t *testing.T) {
+	_, pub, err := randomKeys()
+	require.NoError(t, err)
+
+	handler := jwksHandler(testKey{Kid: "202101", Key: pub})
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// First attempt hangs until client gives up.
+		if atomic.AddInt32(&requests, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithAttemptTimeout(100*time.Millisecond),
+		jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Millisecond)),
+	)
+	require.NoError(t, err)
+
+	start := time.Now()
+	key, err := manager.FetchKey(context.Background(), "202101")
+	require.NoError(t, err)
+	require.Equal(t, "202101", key.Kid)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestManagerCacheHeaders(t *testing.T) {
+	_, pubKey, err := randomKeys()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name         string
+		CacheControl string
+		Requests     int32
+		NotModified  int32
+	}{
+		{
+			Name:         "Fresh",
+			CacheControl: "public, max-age=3600",
+			Requests:     1,
+			NotModified:  0,
+		},
+		{
+			Name:         "Stale",
+			CacheControl: "max-age=0",
+			Requests:     3,
+			NotModified:  2,
+		},
+		{
+			Name:         "NoCache",
+			CacheControl: "no-cache",
+			Requests:     3,
+			NotModified:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			var requests, notModified int32
+			handler := jwksHandler(testKey{"202101", pubKey})
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.Header().Set("Cache-Control", tc.CacheControl)
+				w.Header().Set("ETag", `"v1"`)
+
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					atomic.AddInt32(&notModified, 1)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+
+				handler.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			manager, err := jwks.NewManager(ts.URL)
+			r.NoError(err)
+
+			for i := 0; i < 3; i++ {
+				key, err := manager.FetchKey(context.Background(), "202101")
+				r.NoError(err)
+				r.Equal("202101", key.Kid)
+			}
+
+			r.Equal(tc.Requests, atomic.LoadInt32(&requests))
+			r.Equal(tc.NotModified, atomic.LoadInt32(&notModified))
+		})
+	}
+}
+
+func TestManagerNotModifiedKeepsLifetime(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey, err := randomKeys()
+	r.NoError(err)
+
+	var requests int32
+	handler := jwksHandler(testKey{"202101", pubKey})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+
+		// Not modified response carries no freshness headers.
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "max-age=1")
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			time.Sleep(1100 * time.Millisecond)
+		}
+
+		_, err := manager.FetchKey(context.Background(), "202101")
+		r.NoError(err)
+	}
+
+	r.Equal(int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestManagerRevalidationRateLimit(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey, err := randomKeys()
+	r.NoError(err)
+
+	var requests int32
+	handler := jwksHandler(testKey{"202101", pubKey})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL, jwks.WithMinRefreshInterval(time.Minute))
+	r.NoError(err)
+
+	for i := 0; i < 50; i++ {
+		key, err := manager.FetchKey(context.Background(), "202101")
+		r.NoError(err)
+		r.Equal("202101", key.Kid)
+
+		_, err = manager.FetchKeys(context.Background(), jwks.KeyCriteria{Kty: "RSA"})
+		r.NoError(err)
+	}
+
+	r.Equal(int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestManagerServeStaleOnError(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey, err := randomKeys()
+	r.NoError(err)
+
+	var down int32
+	handler := jwksHandler(testKey{"202101", pubKey})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Expires", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithMaxRetries(2),
+		jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Millisecond)),
+	)
+	r.NoError(err)
+
+	_, err = manager.FetchKey(context.Background(), "202101")
+	r.NoError(err)
+
+	atomic.StoreInt32(&down, 1)
+
+	key, err := manager.FetchKey(context.Background(), "202101")
+	r.NoError(err)
+	r.Equal("202101", key.Kid)
+}
+
+func TestManagerUnknownKeyProtection(t *testing.T) {
+	_, pubKey, err := randomKeys()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name     string
+		Options  []jwks.Option
+		Requests int32
+	}{
+		{
+			Name:     "Default",
+			Requests: 4,
+		},
+		{
+			Name:     "NegativeCache",
+			Options:  []jwks.Option{jwks.WithNegativeCacheTTL(time.Minute)},
+			Requests: 2,
+		},
+		{
+			Name:     "MinRefreshInterval",
+			Options:  []jwks.Option{jwks.WithMinRefreshInterval(time.Minute)},
+			Requests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			var requests int32
+			handler := jwksHandler(testKey{"202101", pubKey})
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				handler.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			manager, err := jwks.NewManager(ts.URL, tc.Options...)
+			r.NoError(err)
+
+			for _, kid := range []string{"202102", "202102", "202103", "202103"} {
+				_, err := manager.FetchKey(context.Background(), kid)
+				r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+			}
+
+			key, err := manager.FetchKey(context.Background(), "202101")
+			r.NoError(err)
+			r.Equal("202101", key.Kid)
+
+			r.Equal(tc.Requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestManagerNegativeCacheExpiration(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey, err := randomKeys()
+	r.NoError(err)
+
+	var requests int32
+	handler := jwksHandler(testKey{"202101", pubKey})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL, jwks.WithNegativeCacheTTL(50*time.Millisecond))
+	r.NoError(err)
+
+	_, err = manager.FetchKey(context.Background(), "202102")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+
+	_, err = manager.FetchKey(context.Background(), "202102")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+	r.Equal(int32(1), atomic.LoadInt32(&requests))
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = manager.FetchKey(context.Background(), "202102")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+	r.Equal(int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestManagerConcurrentFetchKey(t *testing.T) {
+	r := require.New(t)
+
+	var (
+		keys []testKey
+		kids []string
+	)
+
+	for i := 0; i < 5; i++ {
+		_, pubKey, err := randomKeys()
+		r.NoError(err)
+
+		kid := fmt.Sprintf("20210%d", i+1)
+		keys = append(keys, testKey{kid, pubKey})
+		kids = append(kids, kid)
+	}
+
+	var requests int32
+	handler := jwksHandler(keys...)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(kids))
+
+	for _, kid := range kids {
+		wg.Add(1)
+		go func(kid string) {
+			defer wg.Done()
+			_, err := manager.FetchKey(context.Background(), kid)
+			errs <- err
+		}(kid)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		r.NoError(err)
+	}
+
+	r.Equal(int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestManagerSharedFetchCallerCancel(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey, err := randomKeys()
+	r.NoError(err)
+
+	var requests int32
+	handler := jwksHandler(testKey{"202101", pubKey}, testKey{"202102", pubKey})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	impatient := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := manager.FetchKey(ctx, "202101")
+		impatient <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	key, err := manager.FetchKey(context.Background(), "202102")
+	r.NoError(err)
+	r.Equal("202102", key.Kid)
+
+	r.ErrorIs(<-impatient, context.DeadlineExceeded)
+	r.Equal(int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestManagerFetchTimeout(t *testing.T) {
+	r := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithFetchTimeout(100*time.Millisecond),
+		jwks.WithRetryPolicy(jwks.ConstantBackoff(time.Minute)),
+	)
+	r.NoError(err)
+
+	_, err = manager.FetchKey(context.Background(), "202101")
+	r.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func TestManagerFetchPublicKey(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	r.NoError(err)
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+
+	ts := httptest.NewServer(jwksHandler(
+		testKey{"rsa", &rsaKey.PublicKey},
+		testKey{"ec", &ecKey.PublicKey},
+		testKey{"ed", okp.NewEd25519(edPub, nil)},
+	))
+	defer ts.Close()
+
+	testCases := []struct {
+		Name    string
+		Options []jwks.Option
+	}{
+		{
+			Name: "Default",
+		},
+		{
+			Name:    "NoLookup",
+			Options: []jwks.Option{jwks.WithLookup(false)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			manager, err := jwks.NewManager(ts.URL, tc.Options...)
+			r.NoError(err)
+
+			pub, err := manager.FetchPublicKey(ctx, "rsa")
+			r.NoError(err)
+			r.True(rsaKey.PublicKey.Equal(pub))
+
+			pub, err = manager.FetchPublicKey(ctx, "ec")
+			r.NoError(err)
+			r.True(ecKey.PublicKey.Equal(pub))
+
+			pub, err = manager.FetchPublicKey(ctx, "ed")
+			r.NoError(err)
+			r.True(edPub.Equal(pub))
+
+			again, err := manager.FetchPublicKey(ctx, "ed")
+			r.NoError(err)
+			r.Equal(pub, again)
+
+			_, err = manager.FetchPublicKey(ctx, "unknown")
+			r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+		})
+	}
+}
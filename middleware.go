@@ -0,0 +1,213 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrTokenNotFound raises when request has no token.
+	ErrTokenNotFound = errors.New("jwks: token not found")
+	// ErrInvalidRequest raises when request carries token in malformed way.
+	ErrInvalidRequest = errors.New("jwks: invalid authorization request")
+)
+
+type tokenContextKey struct{}
+
+// ContextWithToken returns a copy of context with verified token.
+func ContextWithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// TokenFromContext returns verified token stored by middleware or interceptor.
+func TokenFromContext(ctx context.Context) (*Token, bool) {
+	token, ok := ctx.Value(tokenContextKey{}).(*Token)
+	return token, ok
+}
+
+// ClaimsFromContext returns claims of verified token stored by middleware or interceptor.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return token.Claims, true
+}
+
+// TokenExtractor extracts raw token from request.
+// It returns `ErrTokenNotFound` if request has no token.
+type TokenExtractor func(r *http.Request) (string, error)
+
+// FromAuthHeader extracts token from `Authorization: Bearer` header.
+func FromAuthHeader(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrTokenNotFound
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	if !strings.EqualFold(parts[0], "Bearer") {
+		return "", ErrTokenNotFound
+	}
+
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return "", ErrInvalidRequest
+	}
+
+	return strings.TrimSpace(parts[1]), nil
+}
+
+// FromCookie extracts token from cookie with given name.
+func FromCookie(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		cookie, err := r.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return "", ErrTokenNotFound
+		}
+		return cookie.Value, nil
+	}
+}
+
+// FromQuery extracts token from query parameter with given name.
+func FromQuery(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		values, ok := r.URL.Query()[name]
+		if !ok {
+			return "", ErrTokenNotFound
+		}
+		if len(values) != 1 || values[0] == "" {
+			return "", ErrInvalidRequest
+		}
+		return values[0], nil
+	}
+}
+
+// FromFirst returns token of first extractor which has found one.
+func FromFirst(extractors ...TokenExtractor) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		for _, extract := range extractors {
+			token, err := extract(r)
+			if errors.Is(err, ErrTokenNotFound) {
+				continue
+			}
+			return token, err
+		}
+		return "", ErrTokenNotFound
+	}
+}
+
+// ErrorHandler writes response for failed authentication.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+type middleware struct {
+	verifier     *Verifier
+	extractor    TokenExtractor
+	realm        string
+	errorHandler ErrorHandler
+}
+
+// MiddlewareOption is used for configuring http middleware.
+type MiddlewareOption func(mw *middleware)
+
+// WithTokenExtractor sets token extractor. Default is `FromAuthHeader`.
+func WithTokenExtractor(e TokenExtractor) MiddlewareOption {
+	return func(mw *middleware) { mw.extractor = e }
+}
+
+// WithRealm sets realm of `WWW-Authenticate` challenge.
+func WithRealm(realm string) MiddlewareOption {
+	return func(mw *middleware) { mw.realm = realm }
+}
+
+// WithErrorHandler sets custom handler of authentication errors.
+// Default handler writes RFC 6750 compliant response.
+func WithErrorHandler(h ErrorHandler) MiddlewareOption {
+	return func(mw *middleware) { mw.errorHandler = h }
+}
+
+// Middleware returns http middleware which verifies bearer token and
+// stores it in request context. Use `TokenFromContext` or
+// `ClaimsFromContext` to access it in next handler.
+func Middleware(v *Verifier, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	mw := &middleware{
+		verifier:  v,
+		extractor: FromAuthHeader,
+	}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	if mw.errorHandler == nil {
+		mw.errorHandler = mw.writeError
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, err := mw.extractor(r)
+			if err != nil {
+				mw.errorHandler(w, r, err)
+				return
+			}
+
+			token, err := mw.verifier.Verify(r.Context(), raw)
+			if err != nil {
+				mw.errorHandler(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithToken(r.Context(), token)))
+		})
+	}
+}
+
+// writeError writes error response as defined by RFC 6750.
+func (mw *middleware) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var params []string
+	if mw.realm != "" {
+		params = append(params, `realm="`+sanitizeParam(mw.realm)+`"`)
+	}
+
+	status := http.StatusUnauthorized
+
+	switch {
+	case errors.Is(err, ErrTokenNotFound):
+		// Request lacks any authentication information, so error code is omitted.
+	case errors.Is(err, ErrInvalidRequest):
+		status = http.StatusBadRequest
+		params = append(params, `error="invalid_request"`, `error_description="`+describe(err)+`"`)
+	case errors.Is(err, ErrConnectionFailed), errors.Is(err, ErrDiscoveryFailed),
+		errors.Is(err, ErrPrivateKeyMaterial),
+		errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		// Keys are unavailable, so token cannot be judged. Key set rejected
+		// for leaked secrets is unusable as well.
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	default:
+		params = append(params, `error="invalid_token"`, `error_description="`+describe(err)+`"`)
+	}
+
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+func describe(err error) string {
+	return sanitizeParam(strings.TrimPrefix(err.Error(), "jwks: "))
+}
+
+// sanitizeParam removes characters not allowed in challenge parameter values.
+func sanitizeParam(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, v)
+}
@@ -0,0 +1,167 @@
+package jwks_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(jwksHandler(keys.testKeys()...))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	require.NoError(t, err)
+
+	valid := signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{"sub": "user"}, keys.RSA)
+	invalid := signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{"sub": "user"}, newSigningKeys(t).RSA)
+
+	testCases := []struct {
+		Name      string
+		Options   []jwks.MiddlewareOption
+		Request   func(r *http.Request)
+		Status    int
+		Challenge string
+	}{
+		{
+			Name:    "Header",
+			Request: func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+valid) },
+			Status:  http.StatusOK,
+		},
+		{
+			Name:    "Cookie",
+			Options: []jwks.MiddlewareOption{jwks.WithTokenExtractor(jwks.FromCookie("token"))},
+			Request: func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "token", Value: valid}) },
+			Status:  http.StatusOK,
+		},
+		{
+			Name: "Query",
+			Options: []jwks.MiddlewareOption{
+				jwks.WithTokenExtractor(jwks.FromFirst(jwks.FromAuthHeader, jwks.FromQuery("access_token"))),
+			},
+			Request: func(r *http.Request) { r.URL.RawQuery = "access_token=" + valid },
+			Status:  http.StatusOK,
+		},
+		{
+			Name:      "Missing",
+			Options:   []jwks.MiddlewareOption{jwks.WithRealm("api")},
+			Request:   func(r *http.Request) {},
+			Status:    http.StatusUnauthorized,
+			Challenge: `Bearer realm="api"`,
+		},
+		{
+			Name:      "OtherScheme",
+			Request:   func(r *http.Request) { r.Header.Set("Authorization", "Basic dXNlcjpwYXNz") },
+			Status:    http.StatusUnauthorized,
+			Challenge: `Bearer`,
+		},
+		{
+			Name:      "Malformed",
+			Request:   func(r *http.Request) { r.Header.Set("Authorization", "Bearer ") },
+			Status:    http.StatusBadRequest,
+			Challenge: `Bearer error="invalid_request", error_description="invalid authorization request"`,
+		},
+		{
+			Name:      "InvalidSignature",
+			Options:   []jwks.MiddlewareOption{jwks.WithRealm("api")},
+			Request:   func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+invalid) },
+			Status:    http.StatusUnauthorized,
+			Challenge: `Bearer realm="api", error="invalid_token", error_description="invalid signature"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			handler := jwks.Middleware(jwks.NewVerifier(manager), tc.Options...)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					claims, ok := jwks.ClaimsFromContext(r.Context())
+					if !ok || claims["sub"] != "user" {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				}),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tc.Request(req)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			r.Equal(tc.Status, rec.Code)
+			r.Equal(tc.Challenge, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestMiddlewareKeysUnavailable(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	leaked := httptest.NewServer(rawJWKSHandler(t, rawKey{keys.RSA, map[string]interface{}{"kid": "rsa"}}))
+	defer leaked.Close()
+
+	mux := http.NewServeMux()
+	mismatch := httptest.NewServer(mux)
+	defer mismatch.Close()
+
+	mux.Handle("/.well-known/openid-configuration", metadataHandler("https://other.example.com", mismatch.URL+"/keys"))
+
+	badMux := http.NewServeMux()
+	badURI := httptest.NewServer(badMux)
+	defer badURI.Close()
+
+	badMux.Handle("/.well-known/openid-configuration", metadataHandler(badURI.URL, "::invalid"))
+
+	testCases := []struct {
+		Name    string
+		Manager func() (jwks.Manager, error)
+	}{
+		{
+			Name: "PrivateKeyMaterial",
+			Manager: func() (jwks.Manager, error) {
+				return jwks.NewManager(leaked.URL, jwks.WithPrivateKeyPolicy(jwks.RejectKeySet))
+			},
+		},
+		{
+			Name:    "IssuerMismatch",
+			Manager: func() (jwks.Manager, error) { return jwks.NewManagerFromIssuer(mismatch.URL) },
+		},
+		{
+			Name:    "InvalidJWKSURI",
+			Manager: func() (jwks.Manager, error) { return jwks.NewManagerFromIssuer(badURI.URL) },
+		},
+	}
+
+	token := signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{"sub": "user"}, keys.RSA)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			manager, err := tc.Manager()
+			r.NoError(err)
+
+			handler := jwks.Middleware(jwks.NewVerifier(manager))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			r.Equal(http.StatusServiceUnavailable, rec.Code)
+			r.Empty(rec.Header().Get("WWW-Authenticate"))
+			r.NotContains(rec.Body.String(), "jwks")
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package jwks
+
+import "time"
+
+const _defaultMissCacheSize = 1000
+
+// missed reports whether kid has recently been looked up without success.
+func (m *manager) missed(kid string) bool {
+	if m.missTTL <= 0 {
+		return false
+	}
+
+	v, ok := m.misses.Get(kid)
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(v.(time.Time)) {
+		m.misses.Remove(kid)
+		return false
+	}
+
+	return true
+}
+
+// rememberMiss saves kid that is not present in key set.
+func (m *manager) rememberMiss(kid string) {
+	if m.missTTL <= 0 {
+		return
+	}
+
+	m.misses.Add(kid, time.Now().Add(m.missTTL))
+}
+
+// throttled reports whether remote key set has been refreshed
+// too recently to be downloaded again because of unknown kid.
+func (m *manager) throttled(now time.Time) bool {
+	if m.minRefreshInterval <= 0 || !m.lookup {
+		return false
+	}
+
+	m.mu.RLock()
+	last := m.lastRefresh
+	m.mu.RUnlock()
+
+	return !last.IsZero() && now.Sub(last) < m.minRefreshInterval
+}
+
+// revalidate reports whether stale key set has to be revalidated before
+// cached keys are served. Stale keys are served while refresh is rate limited.
+func (m *manager) revalidate(now time.Time) bool {
+	return m.getValidators().stale(now) && !m.throttled(now)
+}
+
+func (m *manager) touchRefresh(now time.Time) {
+	m.mu.Lock()
+	m.lastRefresh = now
+	m.mu.Unlock()
+}
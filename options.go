@@ -1,7 +1,9 @@
 package jwks
 
 import (
+	"crypto/x509"
 	"net/http"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -29,6 +31,84 @@ func WithMaxRetries(n int) Option {
 	return func(m *manager) { m.retries = n }
 }
 
+// WithRetryPolicy defines delays between failed request attempts.
+// Default is exponential backoff with jitter starting from `100ms` up to `2s`.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(m *manager) { m.retryPolicy = p }
+}
+
+// WithFetchTimeout limits total time of downloading key set including retries.
+// Download is shared between concurrent callers, so it does not depend on
+// their contexts. Default is `30s`.
+func WithFetchTimeout(d time.Duration) Option {
+	return func(m *manager) { m.fetchTimeout = d }
+}
+
+// WithAttemptTimeout limits time of single request attempt. Default is `5s`.
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(m *manager) { m.attemptTimeout = d }
+}
+
+// WithMaxBodySize limits size of key set and discovery document responses.
+// Default is `1MiB`.
+func WithMaxBodySize(n int64) Option {
+	return func(m *manager) { m.maxBodySize = n }
+}
+
+// WithRefreshInterval defines how often key set is refreshed in background
+// after `Start` has been called. Default is `15m`.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(m *manager) { m.refreshInterval = d }
+}
+
+// WithDiscoveryInterval defines how often issuer metadata is re-resolved
+// by manager created with `NewManagerFromIssuer`. Default is `24h`.
+func WithDiscoveryInterval(d time.Duration) Option {
+	return func(m *manager) { m.discoveryInterval = d }
+}
+
+// WithNegativeCacheTTL defines how long unknown kid or unmatched key criteria is remembered and
+// answered with `ErrPublicKeyNotFound` without requesting source. Default is `disabled`.
+func WithNegativeCacheTTL(d time.Duration) Option {
+	return func(m *manager) { m.missTTL = d }
+}
+
+// WithMinRefreshInterval defines minimum interval between source requests
+// caused by unknown kid or stale key set. Default is `disabled`.
+func WithMinRefreshInterval(d time.Duration) Option {
+	return func(m *manager) { m.minRefreshInterval = d }
+}
+
+// WithFetchHook sets function called with report of every downloaded key set.
+func WithFetchHook(h FetchHook) Option {
+	return func(m *manager) { m.fetchHook = h }
+}
+
+// WithX5CValidation requires every key to have `x5c` certificate chain which
+// verifies to one of roots and which leaf holds the key. Keys failing validation
+// are skipped. Extended key usage is not checked unless `opts.KeyUsages` is set.
+func WithX5CValidation(roots *x509.CertPool, opts x509.VerifyOptions) Option {
+	return func(m *manager) {
+		opts.Roots = roots
+		if len(opts.KeyUsages) == 0 {
+			opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+		}
+		m.x5cOptions = &opts
+	}
+}
+
+// WithKeyPolicy sets policy which keys of set must satisfy.
+// Default policy accepts RSA keys of at least `2048` bits.
+func WithKeyPolicy(p KeyPolicy) Option {
+	return func(m *manager) { m.keyPolicy = p }
+}
+
+// WithPrivateKeyPolicy sets handling of private or symmetric key material
+// found in key set. Default is `RejectPrivateKeys`.
+func WithPrivateKeyPolicy(p PrivateKeyPolicy) Option {
+	return func(m *manager) { m.privateKeyPolicy = p }
+}
+
 // WithLogger sets custom logger. Default log level is `disabled`.
 func WithLogger(logger zerolog.Logger) Option {
 	return func(m *manager) { m.logger = logger }
@@ -0,0 +1,60 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+)
+
+const _defaultMinRSABits = 2048
+
+// ErrKeyPolicy raises when key is rejected by `KeyPolicy`.
+var ErrKeyPolicy = errors.New("jwks: key violates policy")
+
+// KeyPolicy defines which keys of fetched set are accepted.
+// Rejected keys are skipped and reported to fetch hook.
+type KeyPolicy struct {
+	// MinRSABits is minimal RSA modulus size. Default is `2048`.
+	MinRSABits int
+	// Curves lists allowed `crv` values of EC and OKP keys.
+	// Empty list allows every curve.
+	Curves []string
+	// Algorithms lists allowed `alg` values. Keys without `alg` member
+	// are accepted. Empty list allows every algorithm.
+	Algorithms []string
+}
+
+// check verifies key and its parsed public part against policy.
+func (p KeyPolicy) check(key *JWK, pub crypto.PublicKey) error {
+	if key.Alg != "" && len(p.Algorithms) > 0 && !containsAny(p.Algorithms, key.Alg) {
+		return fmt.Errorf("%w: algorithm %s is not allowed", ErrKeyPolicy, key.Alg)
+	}
+
+	if key.Crv != "" && len(p.Curves) > 0 && !containsAny(p.Curves, key.Crv) {
+		return fmt.Errorf("%w: curve %s is not allowed", ErrKeyPolicy, key.Crv)
+	}
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		min := p.MinRSABits
+		if min <= 0 {
+			min = _defaultMinRSABits
+		}
+		if bits := k.N.BitLen(); bits < min {
+			return fmt.Errorf("%w: RSA modulus of %d bits is less than %d", ErrKeyPolicy, bits, min)
+		}
+	case *ecdsa.PublicKey:
+		if !k.Curve.IsOnCurve(k.X, k.Y) {
+			return fmt.Errorf("%w: point is not on curve %s", ErrKeyPolicy, k.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: Ed25519 key of %d bytes", ErrKeyPolicy, len(k))
+		}
+	}
+
+	return nil
+}
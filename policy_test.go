@@ -0,0 +1,128 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/rakutentech/jwk-go/okp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerKeyPolicy(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	offCurve := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).Add(keys.ECDSA.X, big.NewInt(1)),
+		Y:     keys.ECDSA.Y,
+	}
+
+	testCases := []struct {
+		Name    string
+		Policy  *jwks.KeyPolicy
+		Key     rawKey
+		Skipped bool
+		Error   error
+	}{
+		{
+			Name: "DefaultPolicy",
+			Key:  rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "key"}},
+		},
+		{
+			Name:  "WeakRSAKey",
+			Key:   rawKey{&weak.PublicKey, map[string]interface{}{"kid": "key"}},
+			Error: jwks.ErrKeyPolicy,
+		},
+		{
+			Name:   "WeakRSAKeyAllowed",
+			Policy: &jwks.KeyPolicy{MinRSABits: 1024},
+			Key:    rawKey{&weak.PublicKey, map[string]interface{}{"kid": "key"}},
+		},
+		{
+			Name:   "StrongerRSAKeyRequired",
+			Policy: &jwks.KeyPolicy{MinRSABits: 3072},
+			Key:    rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "key"}},
+			Error:  jwks.ErrKeyPolicy,
+		},
+		{
+			Name:   "AllowedCurve",
+			Policy: &jwks.KeyPolicy{Curves: []string{"P-256", "Ed25519"}},
+			Key:    rawKey{okp.NewEd25519(keys.Ed25519.Public().(ed25519.PublicKey), nil), map[string]interface{}{"kid": "key"}},
+		},
+		{
+			Name:   "CurveNotAllowed",
+			Policy: &jwks.KeyPolicy{Curves: []string{"P-256"}},
+			Key:    rawKey{&p384.PublicKey, map[string]interface{}{"kid": "key"}},
+			Error:  jwks.ErrKeyPolicy,
+		},
+		{
+			Name:   "AllowedAlgorithm",
+			Policy: &jwks.KeyPolicy{Algorithms: []string{"RS256"}},
+			Key:    rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "key", "alg": "RS256"}},
+		},
+		{
+			Name:   "KeyWithoutAlgorithm",
+			Policy: &jwks.KeyPolicy{Algorithms: []string{"RS256"}},
+			Key:    rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "key"}},
+		},
+		{
+			Name:   "AlgorithmNotAllowed",
+			Policy: &jwks.KeyPolicy{Algorithms: []string{"RS256"}},
+			Key:    rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "key", "alg": "RS384"}},
+			Error:  jwks.ErrKeyPolicy,
+		},
+		{
+			Name: "PointNotOnCurve",
+			Key: rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{
+				"kid": "key",
+				"x":   base64.RawURLEncoding.EncodeToString(offCurve.X.FillBytes(make([]byte, 32))),
+			}},
+			Skipped: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ts := httptest.NewServer(rawJWKSHandler(t, tc.Key))
+			defer ts.Close()
+
+			var report jwks.FetchReport
+			opts := []jwks.Option{
+				jwks.WithFetchHook(func(r jwks.FetchReport) { report = r }),
+			}
+			if tc.Policy != nil {
+				opts = append(opts, jwks.WithKeyPolicy(*tc.Policy))
+			}
+
+			mng, err := jwks.NewManager(ts.URL, opts...)
+			require.NoError(t, err)
+
+			key, err := mng.FetchKey(context.Background(), "key")
+			if tc.Skipped || tc.Error != nil {
+				require.ErrorIs(t, err, jwks.ErrPublicKeyNotFound)
+				require.Len(t, report.Skipped, 1)
+				if tc.Error != nil {
+					require.ErrorIs(t, report.Skipped[0].Err, tc.Error)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "key", key.Kid)
+		})
+	}
+}
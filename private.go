@@ -0,0 +1,174 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+)
+
+// ErrPrivateKeyMaterial raises when key set contains private or symmetric keys.
+var ErrPrivateKeyMaterial = errors.New("jwks: key set contains private key material")
+
+// privateMembers are JWK members holding private or symmetric key material.
+var privateMembers = []string{"d", "p", "q", "dp", "dq", "qi", "oth", "k"}
+
+// PrivateKeyPolicy defines how private or symmetric key material
+// found in remote key set is handled.
+type PrivateKeyPolicy int
+
+const (
+	// RejectPrivateKeys skips keys holding private or symmetric material.
+	// Previously fetched copies of such keys are discarded as well.
+	RejectPrivateKeys PrivateKeyPolicy = iota
+	// StripPrivateKeys removes private members and keeps public part of key.
+	// Symmetric keys have no public part, so they are skipped.
+	StripPrivateKeys
+	// RejectKeySet discards the whole set if any key holds private or symmetric material.
+	// Keys of previously fetched sets are discarded as well.
+	RejectKeySet
+)
+
+// LeakedKey describes key which has been published with private or symmetric material.
+type LeakedKey struct {
+	// Index is a position of key in set.
+	Index int
+	Kid   string
+	Kty   string
+	// Members lists private members found in key.
+	Members []string
+}
+
+// findPrivate returns leaked key description if key holds private or symmetric material.
+func findPrivate(i int, raw json.RawMessage) (*LeakedKey, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, err
+	}
+
+	leaked := &LeakedKey{Index: i}
+	json.Unmarshal(members["kid"], &leaked.Kid)
+	json.Unmarshal(members["kty"], &leaked.Kty)
+
+	for _, name := range privateMembers {
+		if _, ok := members[name]; ok {
+			leaked.Members = append(leaked.Members, name)
+		}
+	}
+
+	if len(leaked.Members) == 0 && leaked.Kty != "oct" {
+		return nil, nil
+	}
+
+	return leaked, nil
+}
+
+// stripPrivate removes private members from key.
+func stripPrivate(raw json.RawMessage) (json.RawMessage, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, err
+	}
+
+	for _, name := range privateMembers {
+		delete(members, name)
+	}
+
+	return json.Marshal(members)
+}
+
+// screenPrivate applies private key policy to keys of set. Returned keys have
+// private members removed if they are stripped and are nil if they are skipped.
+func (m *manager) screenPrivate(ctx context.Context, report *FetchReport, keys []json.RawMessage) ([]json.RawMessage, error) {
+	screened := make([]json.RawMessage, len(keys))
+
+	for i, raw := range keys {
+		leaked, err := findPrivate(i, raw)
+		if err != nil {
+			m.skipKey(report, i, raw, err)
+			continue
+		}
+
+		if leaked == nil {
+			screened[i] = raw
+			continue
+		}
+
+		m.logger.Warn().Msgf("key %d (kid %q, kty %q) of %s holds private key material %v",
+			i, leaked.Kid, leaked.Kty, report.URL, leaked.Members)
+		report.Leaked = append(report.Leaked, *leaked)
+
+		if m.privateKeyPolicy != StripPrivateKeys || leaked.Kty == "oct" {
+			m.skipKey(report, i, raw, ErrPrivateKeyMaterial)
+			// Anyone can sign with leaked key now, so its earlier copy is not served either.
+			m.forgetKey(ctx, leakedKid(leaked, raw))
+			continue
+		}
+
+		if screened[i], err = stripPrivate(raw); err != nil {
+			m.skipKey(report, i, raw, err)
+		}
+	}
+
+	// Provider has leaked secrets, so none of its keys can be trusted,
+	// including those fetched before. Refresh forgets them.
+	if len(report.Leaked) > 0 && m.privateKeyPolicy == RejectKeySet {
+		m.logger.Warn().Msgf("rejecting key set of %s holding private key material", report.URL)
+		return nil, ErrPrivateKeyMaterial
+	}
+
+	return screened, nil
+}
+
+// leakedKid returns kid under which leaked key has been saved before.
+// Key without kid is identified by its thumbprint.
+func leakedKid(leaked *LeakedKey, raw json.RawMessage) string {
+	if leaked.Kid != "" {
+		return leaked.Kid
+	}
+
+	spec, _, err := parseKey(raw)
+	if err != nil {
+		return ""
+	}
+
+	tp, err := thumbprint(spec, crypto.SHA256)
+	if err != nil {
+		return ""
+	}
+
+	return tp
+}
+
+// forgetKey drops previously fetched key from cache and lookup indexes.
+func (m *manager) forgetKey(ctx context.Context, kid string) {
+	if kid == "" {
+		return
+	}
+
+	if err := m.cache.Remove(ctx, kid); err != nil {
+		m.logger.Debug().Msgf("failed cache removal of %s with %v", kid, err)
+	}
+	m.parsed.Remove(kid)
+
+	entries := m.getEntries()
+	kept := make([]keyEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.key.Kid != kid {
+			kept = append(kept, e)
+		}
+	}
+	m.setEntries(ctx, kept)
+}
+
+// forget drops keys of previously fetched sets, so they are not served
+// anymore, even as stale.
+func (m *manager) forget(ctx context.Context) {
+	if err := m.cache.Purge(ctx); err != nil {
+		m.logger.Debug().Msgf("failed cache purge with %v", err)
+	}
+	m.parsed.Purge()
+
+	m.setEntries(ctx, nil)
+	m.setValidators(validators{})
+}
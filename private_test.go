@@ -0,0 +1,195 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerPrivateKeyPolicy(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(rawJWKSHandler(t,
+		rawKey{keys.RSA, map[string]interface{}{"kid": "rsa"}},
+		rawKey{[]byte("secret"), map[string]interface{}{"kid": "hmac"}},
+		rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec"}},
+	))
+	defer ts.Close()
+
+	testCases := []struct {
+		Name    string
+		Policy  jwks.PrivateKeyPolicy
+		Kids    map[string]error
+		Skipped int
+	}{
+		{
+			Name:   "RejectPrivateKeys",
+			Policy: jwks.RejectPrivateKeys,
+			Kids: map[string]error{
+				"rsa":  jwks.ErrPublicKeyNotFound,
+				"hmac": jwks.ErrPublicKeyNotFound,
+				"ec":   nil,
+			},
+			Skipped: 2,
+		},
+		{
+			Name:   "StripPrivateKeys",
+			Policy: jwks.StripPrivateKeys,
+			Kids: map[string]error{
+				"rsa":  nil,
+				"hmac": jwks.ErrPublicKeyNotFound,
+				"ec":   nil,
+			},
+			Skipped: 1,
+		},
+		{
+			Name:   "RejectKeySet",
+			Policy: jwks.RejectKeySet,
+			Kids: map[string]error{
+				"rsa":  jwks.ErrPrivateKeyMaterial,
+				"hmac": jwks.ErrPrivateKeyMaterial,
+				"ec":   jwks.ErrPrivateKeyMaterial,
+			},
+			Skipped: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			for kid, expected := range tc.Kids {
+				var report jwks.FetchReport
+				mng, err := jwks.NewManager(ts.URL,
+					jwks.WithPrivateKeyPolicy(tc.Policy),
+					jwks.WithFetchHook(func(r jwks.FetchReport) { report = r }),
+				)
+				require.NoError(t, err)
+
+				key, err := mng.FetchKey(context.Background(), kid)
+				if expected != nil {
+					require.ErrorIs(t, err, expected)
+				} else {
+					require.NoError(t, err)
+					require.Nil(t, key.D)
+					require.Nil(t, key.P)
+					require.Nil(t, key.Q)
+				}
+
+				require.Len(t, report.Leaked, 2)
+				require.Equal(t, "rsa", report.Leaked[0].Kid)
+				require.Equal(t, []string{"d", "p", "q", "dp", "dq", "qi"}, report.Leaked[0].Members)
+				require.Equal(t, "hmac", report.Leaked[1].Kid)
+				require.Len(t, report.Skipped, tc.Skipped)
+			}
+		})
+	}
+}
+
+func TestManagerRejectKeySetForgetsKeys(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	good := rawJWKSHandler(t, rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec"}})
+	leaked := rawJWKSHandler(t,
+		rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec"}},
+		rawKey{keys.RSA, map[string]interface{}{"kid": "rsa"}},
+	)
+
+	var leaking int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Key set is stale right away, so every lookup revalidates it.
+		w.Header().Set("Expires", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		if atomic.LoadInt32(&leaking) == 1 {
+			leaked.ServeHTTP(w, req)
+			return
+		}
+		good.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	mng, err := jwks.NewManager(ts.URL, jwks.WithPrivateKeyPolicy(jwks.RejectKeySet))
+	r.NoError(err)
+
+	ctx := context.Background()
+
+	_, err = mng.FetchKey(ctx, "ec")
+	r.NoError(err)
+
+	atomic.StoreInt32(&leaking, 1)
+
+	_, err = mng.FetchKey(ctx, "ec")
+	r.ErrorIs(err, jwks.ErrPrivateKeyMaterial)
+	r.NotErrorIs(err, jwks.ErrConnectionFailed)
+
+	_, err = mng.FetchKeys(ctx, jwks.KeyCriteria{Kty: "EC"})
+	r.ErrorIs(err, jwks.ErrPrivateKeyMaterial)
+
+	size, err := mng.CacheSize(ctx)
+	r.NoError(err)
+	r.Zero(size)
+}
+
+func TestManagerForgetsLeakedKeys(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	clean := rawJWKSHandler(t,
+		rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa"}},
+		rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{}},
+	)
+	leaked := rawJWKSHandler(t,
+		rawKey{keys.RSA, map[string]interface{}{"kid": "rsa"}},
+		rawKey{keys.ECDSA, map[string]interface{}{}},
+	)
+
+	var leaking int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&leaking) == 1 {
+			leaked.ServeHTTP(w, req)
+			return
+		}
+		clean.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	var report jwks.FetchReport
+	mng, err := jwks.NewManager(ts.URL, jwks.WithFetchHook(func(r jwks.FetchReport) { report = r }))
+	r.NoError(err)
+
+	ctx := context.Background()
+
+	_, err = mng.FetchPublicKey(ctx, "rsa")
+	r.NoError(err)
+
+	keySet, err := mng.FetchKeys(ctx, jwks.KeyCriteria{Kty: "EC"})
+	r.NoError(err)
+	r.Len(keySet, 1)
+
+	tp, err := jwks.Thumbprint(keySet[0], crypto.SHA256)
+	r.NoError(err)
+
+	atomic.StoreInt32(&leaking, 1)
+
+	// Unknown kid causes key set to be downloaded again.
+	_, err = mng.FetchKey(ctx, "other")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+	r.Len(report.Leaked, 2)
+
+	_, err = mng.FetchKey(ctx, "rsa")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+
+	_, err = mng.FetchPublicKey(ctx, "rsa")
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+
+	_, err = mng.FetchKey(ctx, tp)
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+
+	_, err = mng.FetchKeyByThumbprint(ctx, tp, crypto.SHA256)
+	r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+}
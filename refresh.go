@@ -0,0 +1,137 @@
+package jwks
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultRefreshInterval = 15 * time.Minute
+	_minRefreshDelay        = 30 * time.Second
+)
+
+// session scopes shared downloads, so that they are cancelled and
+// awaited on Close.
+type session struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newSession() *session {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &session{ctx: ctx, cancel: cancel}
+}
+
+// join registers download in current session.
+func (m *manager) join() *session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.session.wg.Add(1)
+	return m.session
+}
+
+// Start runs background refresh of key set until context is done or
+// manager is closed. Key set is downloaded immediately and then on
+// interval or ahead of expiration defined by source cache headers.
+// Refresh can be started again once it has stopped.
+func (m *manager) Start(ctx context.Context) error {
+	if !m.lookup {
+		return ErrLookupDisabled
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.done != nil {
+		return ErrRefresherStarted
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go m.run(ctx, m.done)
+
+	return nil
+}
+
+// Close stops background refresh, cancels running downloads and waits
+// for them to finish. Manager remains usable after Close.
+func (m *manager) Close() error {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	s := m.session
+	m.session = newSession()
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.cancel()
+	s.wg.Wait()
+
+	return nil
+}
+
+func (m *manager) run(ctx context.Context, done chan struct{}) {
+	defer func() {
+		m.mu.Lock()
+		if m.done == done {
+			m.cancel, m.done = nil, nil
+		}
+		m.mu.Unlock()
+
+		close(done)
+	}()
+
+	for {
+		m.logger.Debug().Msg("refreshing jwks in background")
+
+		delay := m.minRefreshDelay()
+		if _, err := m.fetchSet(ctx, true); err != nil {
+			m.logger.Debug().Msgf("background refresh failed with %v", err)
+		} else {
+			delay = m.refreshDelay(time.Now())
+		}
+
+		m.logger.Debug().Msgf("next background refresh in %s", delay)
+		if err := sleep(ctx, delay); err != nil {
+			m.logger.Debug().Msg("background refresh stopped")
+			return
+		}
+	}
+}
+
+// refreshDelay returns time to wait before next refresh. Refresh is done
+// on interval or a bit earlier than key set expires.
+func (m *manager) refreshDelay(now time.Time) time.Duration {
+	d := m.refreshInterval
+
+	if expires := m.getValidators().expires; !expires.IsZero() {
+		ahead := expires.Sub(now)
+		ahead -= ahead / 10
+		if ahead < d {
+			d = ahead
+		}
+	}
+
+	if min := m.minRefreshDelay(); d < min {
+		d = min
+	}
+
+	return d
+}
+
+// minRefreshDelay protects source from being hammered by refresher
+// if it responds with very short or zero freshness lifetime.
+func (m *manager) minRefreshDelay() time.Duration {
+	if m.refreshInterval > 0 && m.refreshInterval < _minRefreshDelay {
+		return m.refreshInterval
+	}
+	return _minRefreshDelay
+}
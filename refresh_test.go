@@ -0,0 +1,176 @@
+package jwks_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerBackgroundRefresh(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	_, pubKey1, err := randomKeys()
+	r.NoError(err)
+
+	_, pubKey2, err := randomKeys()
+	r.NoError(err)
+
+	var rotated, requests int32
+	before := jwksHandler(testKey{"202101", pubKey1})
+	after := jwksHandler(testKey{"202101", pubKey1}, testKey{"202102", pubKey2})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&rotated) == 1 {
+			after.ServeHTTP(w, r)
+			return
+		}
+		before.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL, jwks.WithRefreshInterval(50*time.Millisecond))
+	r.NoError(err)
+	r.NoError(manager.Start(ctx))
+	r.ErrorIs(manager.Start(ctx), jwks.ErrRefresherStarted)
+
+	r.Eventually(func() bool {
+		n, _ := manager.CacheSize(ctx)
+		return n == 1
+	}, time.Second, 10*time.Millisecond)
+
+	atomic.StoreInt32(&rotated, 1)
+
+	r.Eventually(func() bool {
+		n, _ := manager.CacheSize(ctx)
+		return n == 2
+	}, time.Second, 10*time.Millisecond)
+
+	r.NoError(manager.Close())
+	r.NoError(manager.Close())
+
+	n := atomic.LoadInt32(&requests)
+	time.Sleep(150 * time.Millisecond)
+	r.Equal(n, atomic.LoadInt32(&requests))
+}
+
+func TestManagerBackgroundRefreshContext(t *testing.T) {
+	r := require.New(t)
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithMaxRetries(1),
+		jwks.WithRefreshInterval(20*time.Millisecond),
+	)
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.NoError(manager.Start(ctx))
+
+	r.Eventually(func() bool {
+		return atomic.LoadInt32(&requests) >= 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	r.NoError(manager.Close())
+
+	n := atomic.LoadInt32(&requests)
+	time.Sleep(100 * time.Millisecond)
+	r.Equal(n, atomic.LoadInt32(&requests))
+}
+
+func TestManagerBackgroundRefreshNoLookup(t *testing.T) {
+	manager, err := jwks.NewManager("https://example.com/.well-known/jwks.json", jwks.WithLookup(false))
+	require.NoError(t, err)
+	require.ErrorIs(t, manager.Start(context.Background()), jwks.ErrLookupDisabled)
+}
+
+func TestManagerRestart(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	_, pubKey, err := randomKeys()
+	r.NoError(err)
+
+	ts := httptest.NewServer(jwksHandler(testKey{"202101", pubKey}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	r.NoError(manager.Start(ctx))
+	r.NoError(manager.Close())
+
+	r.NoError(manager.Start(ctx))
+	r.NoError(manager.Close())
+
+	// Refresher stopped by its context may be started again as well.
+	stopped, cancel := context.WithCancel(ctx)
+	r.NoError(manager.Start(stopped))
+	cancel()
+
+	r.Eventually(func() bool {
+		err := manager.Start(ctx)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	r.NoError(manager.Close())
+
+	key, err := manager.FetchKey(ctx, "202101")
+	r.NoError(err)
+	r.Equal("202101", key.Kid)
+}
+
+func TestManagerCloseCancelsDownload(t *testing.T) {
+	r := require.New(t)
+
+	started := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	var hooks int32
+	manager, err := jwks.NewManager(ts.URL,
+		jwks.WithMaxRetries(1),
+		jwks.WithFetchHook(func(jwks.FetchReport) { atomic.AddInt32(&hooks, 1) }),
+	)
+	r.NoError(err)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := manager.FetchKey(context.Background(), "202101")
+		errs <- err
+	}()
+
+	<-started
+
+	start := time.Now()
+	r.NoError(manager.Close())
+	r.Less(int64(time.Since(start)), int64(time.Second))
+
+	// Download has finished before Close returned.
+	select {
+	case err := <-errs:
+		r.ErrorIs(err, context.Canceled)
+	case <-time.After(time.Second):
+		r.Fail("download is still running")
+	}
+	r.Zero(atomic.LoadInt32(&hooks))
+}
@@ -0,0 +1,194 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const _defaultMaxIssuers = 100
+
+// ErrUnknownIssuer raises when issuer is not allowed by registry.
+var ErrUnknownIssuer = errors.New("jwks: unknown issuer")
+
+// Registry holds key managers of multiple issuers. Managers are created
+// lazily on first request and share http client and cache.
+type Registry struct {
+	mu       sync.Mutex
+	issuers  map[string]string
+	patterns []*regexp.Regexp
+	managers map[string]Manager
+	// matched holds managers of issuers matching patterns. Issuer comes
+	// from unverified token, so least recently used managers are evicted.
+	matched *lru.Cache
+
+	client     *http.Client
+	cache      *lru.Cache
+	cacheSize  int
+	maxIssuers int
+	opts       []Option
+}
+
+// RegistryOption is used for configuring registry.
+type RegistryOption func(r *Registry)
+
+// WithIssuer allows issuer which JWKS URL is resolved using discovery.
+func WithIssuer(issuer string) RegistryOption {
+	return func(r *Registry) { r.issuers[issuer] = "" }
+}
+
+// WithIssuerJWKS allows issuer with known JWKS URL.
+func WithIssuerJWKS(issuer, rawurl string) RegistryOption {
+	return func(r *Registry) { r.issuers[issuer] = rawurl }
+}
+
+// WithIssuerPattern allows every issuer matching pattern. JWKS URL is
+// resolved using discovery. Make sure that pattern is anchored.
+func WithIssuerPattern(re *regexp.Regexp) RegistryOption {
+	return func(r *Registry) { r.patterns = append(r.patterns, re) }
+}
+
+// WithRegistryHTTPClient sets http client shared by managers.
+func WithRegistryHTTPClient(c *http.Client) RegistryOption {
+	return func(r *Registry) { r.client = c }
+}
+
+// WithRegistryCacheSize defines total number of keys cached for all issuers. Default is `100`.
+func WithRegistryCacheSize(n int) RegistryOption {
+	return func(r *Registry) { r.cacheSize = n }
+}
+
+// WithMaxIssuers limits number of managers created for issuers matching patterns.
+// Least recently used managers are closed and evicted. Default is `100`.
+func WithMaxIssuers(n int) RegistryOption {
+	return func(r *Registry) { r.maxIssuers = n }
+}
+
+// WithManagerOptions sets options applied to every created manager.
+func WithManagerOptions(opts ...Option) RegistryOption {
+	return func(r *Registry) { r.opts = append(r.opts, opts...) }
+}
+
+// NewRegistry returns a new instance of `Registry`.
+func NewRegistry(opts ...RegistryOption) (*Registry, error) {
+	reg := &Registry{
+		issuers:    make(map[string]string),
+		managers:   make(map[string]Manager),
+		client:     &http.Client{Timeout: _defaultTimeout},
+		cacheSize:  _defaultCacheSize,
+		maxIssuers: _defaultMaxIssuers,
+	}
+
+	for _, opt := range opts {
+		opt(reg)
+	}
+
+	cache, err := lru.New(reg.cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	reg.cache = cache
+
+	matched, err := lru.NewWithEvict(reg.maxIssuers, func(_, v interface{}) {
+		v.(Manager).Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	reg.matched = matched
+
+	return reg, nil
+}
+
+// FetchKey returns key of issuer by kid.
+func (r *Registry) FetchKey(ctx context.Context, iss, kid string) (*JWK, error) {
+	m, err := r.Manager(iss)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.FetchKey(ctx, kid)
+}
+
+// Manager returns key manager of issuer creating it if needed.
+func (r *Registry) Manager(iss string) (Manager, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.managers[iss]; ok {
+		return m, nil
+	}
+	if m, ok := r.matched.Get(iss); ok {
+		return m.(Manager), nil
+	}
+
+	rawurl, configured := r.issuers[iss]
+	if !configured && !r.matches(iss) {
+		return nil, ErrUnknownIssuer
+	}
+
+	opts := append([]Option{
+		WithHTTPClient(r.client),
+		WithCache(&sharedCache{ns: iss + "\x00", cache: r.cache}),
+	}, r.opts...)
+
+	var (
+		m   Manager
+		err error
+	)
+
+	if rawurl != "" {
+		m, err = NewManager(rawurl, opts...)
+	} else {
+		m, err = NewManagerFromIssuer(iss, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if configured {
+		r.managers[iss] = m
+	} else {
+		r.matched.Add(iss, m)
+	}
+
+	return m, nil
+}
+
+func (r *Registry) matches(iss string) bool {
+	for _, re := range r.patterns {
+		if re.MatchString(iss) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops background refresh of all managers.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	managers := make([]Manager, 0, len(r.managers)+r.matched.Len())
+	for _, m := range r.managers {
+		managers = append(managers, m)
+	}
+	for _, iss := range r.matched.Keys() {
+		if m, ok := r.matched.Peek(iss); ok {
+			managers = append(managers, m.(Manager))
+		}
+	}
+
+	var err error
+	for _, m := range managers {
+		if cerr := m.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,100 @@
+package jwks_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryFetchKey(t *testing.T) {
+	r := require.New(t)
+
+	_, pubKey1, err := randomKeys()
+	r.NoError(err)
+
+	_, pubKey2, err := randomKeys()
+	r.NoError(err)
+
+	// Both issuers publish key with the same kid.
+	ts1 := httptest.NewServer(jwksHandler(testKey{"202101", pubKey1}))
+	defer ts1.Close()
+
+	mux := http.NewServeMux()
+	ts2 := httptest.NewServer(mux)
+	defer ts2.Close()
+
+	mux.Handle("/.well-known/openid-configuration", metadataHandler(ts2.URL, ts2.URL+"/keys"))
+	mux.Handle("/keys", jwksHandler(testKey{"202101", pubKey2}))
+
+	registry, err := jwks.NewRegistry(
+		jwks.WithIssuerJWKS("https://tenant1.example.com", ts1.URL),
+		jwks.WithIssuerPattern(regexp.MustCompile(`^http://127\.0\.0\.1:\d+$`)),
+	)
+	r.NoError(err)
+	defer registry.Close()
+
+	ctx := context.Background()
+
+	key1, err := registry.FetchKey(ctx, "https://tenant1.example.com", "202101")
+	r.NoError(err)
+
+	key2, err := registry.FetchKey(ctx, ts2.URL, "202101")
+	r.NoError(err)
+	r.NotEqual(key1.N, key2.N)
+
+	m1, err := registry.Manager("https://tenant1.example.com")
+	r.NoError(err)
+
+	size, err := m1.CacheSize(ctx)
+	r.NoError(err)
+	r.Equal(1, size)
+
+	_, err = registry.FetchKey(ctx, "https://evil.example.com", "202101")
+	r.ErrorIs(err, jwks.ErrUnknownIssuer)
+}
+
+func TestRegistryMaxIssuers(t *testing.T) {
+	r := require.New(t)
+
+	registry, err := jwks.NewRegistry(
+		jwks.WithIssuer("https://example.com"),
+		jwks.WithIssuerPattern(regexp.MustCompile(`^https://[a-z0-9]+\.example\.com$`)),
+		jwks.WithMaxIssuers(2),
+	)
+	r.NoError(err)
+
+	configured, err := registry.Manager("https://example.com")
+	r.NoError(err)
+
+	tenant, err := registry.Manager("https://tenant.example.com")
+	r.NoError(err)
+
+	// Unverified tokens may carry any number of matching issuers.
+	for i := 0; i < 10; i++ {
+		_, err = registry.Manager(fmt.Sprintf("https://fake%d.example.com", i))
+		r.NoError(err)
+	}
+
+	// Least recently used manager has been replaced.
+	again, err := registry.Manager("https://tenant.example.com")
+	r.NoError(err)
+	r.True(tenant != again)
+
+	// Configured issuers are never evicted.
+	same, err := registry.Manager("https://example.com")
+	r.NoError(err)
+	r.True(configured == same)
+
+	r.NoError(registry.Close())
+}
+
+func TestRegistryInvalidCacheSize(t *testing.T) {
+	_, err := jwks.NewRegistry(jwks.WithRegistryCacheSize(-1))
+	require.Error(t, err)
+}
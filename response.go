@@ -0,0 +1,78 @@
+package jwks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+const (
+	// _defaultMaxBodySize is large enough for key sets with long certificate chains.
+	_defaultMaxBodySize = 1 << 20
+	// _maxDrainSize limits unread body discarded for connection reuse.
+	_maxDrainSize = 64 << 10
+)
+
+var (
+	// ErrResponseTooLarge raises when response body exceeds maximum size.
+	ErrResponseTooLarge = errors.New("jwks: response body is too large")
+	// ErrUnexpectedContentType raises when key set is not served as json.
+	ErrUnexpectedContentType = errors.New("jwks: unexpected content type")
+)
+
+// jwksMediaTypes are accepted content types of key set.
+var jwksMediaTypes = []string{"application/json", "application/jwk-set+json"}
+
+// checkContentType verifies that response holds key set.
+func checkContentType(h http.Header) error {
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !containsAny(jwksMediaTypes, mediaType) {
+		return ErrUnexpectedContentType
+	}
+	return nil
+}
+
+// decodeBody decodes json body which is not allowed to exceed max bytes.
+func decodeBody(resp *http.Response, max int64, v interface{}) error {
+	if resp.ContentLength > max {
+		return ErrResponseTooLarge
+	}
+
+	return json.NewDecoder(&limitedReader{r: resp.Body, n: max}).Decode(v)
+}
+
+// drainBody discards rest of body, so that connection can be reused, and closes it.
+func drainBody(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, io.LimitReader(body, _maxDrainSize))
+	body.Close()
+}
+
+// limitedReader reads up to n bytes and fails with `ErrResponseTooLarge`
+// if underlying reader has more.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+
+	return n, err
+}
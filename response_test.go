@@ -0,0 +1,114 @@
+package jwks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/rakutentech/jwk-go/jwk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerResponseLimits(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	set := jwk.KeySpecSet{Keys: []jwk.KeySpec{*jwk.NewSpecWithID("rsa", &keys.RSA.PublicKey)}}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	// Padding is valid json whitespace, so only size makes the body invalid.
+	padded := append([]byte(strings.Repeat(" ", 4096)), data...)
+
+	testCases := []struct {
+		Name        string
+		ContentType string
+		Body        []byte
+		Chunked     bool
+		MaxBodySize int64
+		Error       error
+	}{
+		{
+			Name:        "JSON",
+			ContentType: "application/json",
+			Body:        data,
+		},
+		{
+			Name:        "JWKSet",
+			ContentType: "application/jwk-set+json; charset=utf-8",
+			Body:        data,
+		},
+		{
+			Name:        "HTML",
+			ContentType: "text/html",
+			Body:        data,
+			Error:       jwks.ErrUnexpectedContentType,
+		},
+		{
+			Name:  "NoContentType",
+			Body:  data,
+			Error: jwks.ErrUnexpectedContentType,
+		},
+		{
+			Name:        "WithinLimit",
+			ContentType: "application/json",
+			Body:        data,
+			MaxBodySize: int64(len(data)),
+		},
+		{
+			Name:        "ContentLengthExceeded",
+			ContentType: "application/json",
+			Body:        padded,
+			MaxBodySize: int64(len(data)) + 1024,
+			Error:       jwks.ErrResponseTooLarge,
+		},
+		{
+			Name:        "ChunkedBodyExceeded",
+			ContentType: "application/json",
+			Body:        padded,
+			Chunked:     true,
+			MaxBodySize: int64(len(data)) + 1024,
+			Error:       jwks.ErrResponseTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var requests int32
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+
+				w.Header()["Content-Type"] = []string{tc.ContentType}
+				if tc.Chunked {
+					// Flushing before body is written omits Content-Length.
+					w.(http.Flusher).Flush()
+				}
+				w.Write(tc.Body)
+			}))
+			defer ts.Close()
+
+			opts := []jwks.Option{}
+			if tc.MaxBodySize > 0 {
+				opts = append(opts, jwks.WithMaxBodySize(tc.MaxBodySize))
+			}
+
+			mng, err := jwks.NewManager(ts.URL, opts...)
+			require.NoError(t, err)
+
+			key, err := mng.FetchKey(context.Background(), "rsa")
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+				// Limits are not transient, so request is not repeated.
+				require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "rsa", key.Kid)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/rakutentech/jwk-go/jwk"
+
+	// Register hash functions used by thumbprints.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+)
+
+// ErrUnsupportedHash raises when thumbprint hash function is not available.
+var ErrUnsupportedHash = errors.New("jwks: unsupported thumbprint hash")
+
+// indexedHashes are thumbprint hashes computed for every fetched key.
+// SHA-1 is kept for legacy pinning only.
+var indexedHashes = []crypto.Hash{crypto.SHA256, crypto.SHA1}
+
+// Thumbprint returns base64url encoded RFC 7638 thumbprint of key.
+func Thumbprint(key *JWK, hash crypto.Hash) (string, error) {
+	spec, err := key.ParseKeySpec()
+	if err != nil {
+		return "", err
+	}
+
+	return thumbprint(spec, hash)
+}
+
+func thumbprint(spec *jwk.KeySpec, hash crypto.Hash) (string, error) {
+	if !hash.Available() {
+		return "", ErrUnsupportedHash
+	}
+
+	tp, err := spec.ThumbprintUsing(hash.New())
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tp), nil
+}
+
+func thumbprintIndex(hash crypto.Hash, tp string) string {
+	return "jkt:" + hash.String() + ":" + tp
+}
+
+func x5tIndex(x5t string) string {
+	return "x5t:" + x5t
+}
+
+// buildIndex maps alternate identifiers of keys to keys.
+// First key wins if identifiers collide.
+func buildIndex(entries []keyEntry) map[string]*JWK {
+	index := make(map[string]*JWK)
+
+	add := func(name string, key *JWK) {
+		if _, ok := index[name]; !ok {
+			index[name] = key
+		}
+	}
+
+	for _, e := range entries {
+		for h, tp := range e.thumbprints {
+			add(thumbprintIndex(h, tp), e.key)
+		}
+		if e.params.X5t != "" {
+			add(x5tIndex(e.params.X5t), e.key)
+		}
+		if e.params.X5tS256 != "" {
+			add(x5tIndex(e.params.X5tS256), e.key)
+		}
+	}
+
+	return index
+}
+
+// FetchKeyByThumbprint returns key by its base64url encoded RFC 7638 thumbprint.
+// Only `crypto.SHA256` and `crypto.SHA1` thumbprints are indexed.
+func (m *manager) FetchKeyByThumbprint(ctx context.Context, tp string, hash crypto.Hash) (*JWK, error) {
+	indexed := false
+	for _, h := range indexedHashes {
+		indexed = indexed || h == hash
+	}
+	if !indexed {
+		return nil, ErrUnsupportedHash
+	}
+
+	return m.fetchIndexed(ctx, thumbprintIndex(hash, tp))
+}
+
+// FetchKeyByX5t returns key by `x5t` or `x5t#S256` certificate thumbprint.
+func (m *manager) FetchKeyByX5t(ctx context.Context, x5t string) (*JWK, error) {
+	return m.fetchIndexed(ctx, x5tIndex(x5t))
+}
+
+func (m *manager) fetchIndexed(ctx context.Context, name string) (*JWK, error) {
+	var stale *JWK
+	if m.lookup {
+		if key := m.indexed(name); key != nil && m.valid(key.Kid, time.Now()) {
+			if !m.revalidate(time.Now()) {
+				return key, nil
+			}
+			stale = key
+		} else if m.throttled(time.Now()) {
+			m.logger.Debug().Msg("jwks refresh for thumbprint lookup is rate limited")
+			return nil, ErrPublicKeyNotFound
+		}
+	}
+
+	if _, err := m.fetchSet(ctx, m.lookup && len(m.getEntries()) > 0); err != nil {
+		if stale != nil && !errors.Is(err, ErrPrivateKeyMaterial) {
+			m.logger.Debug().Msgf("revalidation failed with %v, serving stale key", err)
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	if key := m.indexed(name); key != nil {
+		return m.checkValidity(key, nil)
+	}
+
+	return nil, ErrPublicKeyNotFound
+}
+
+func (m *manager) indexed(name string) *JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.index[name]
+}
@@ -0,0 +1,156 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/rakutentech/jwk-go/jwk"
+	"github.com/stretchr/testify/require"
+)
+
+// Example key of RFC 7638, section 3.1.
+const (
+	rfc7638N   = "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	rfc7638Key = `{"kty":"RSA","n":"` + rfc7638N + `","e":"AQAB","alg":"RS256","kid":"2011-04-29"}`
+)
+
+func TestThumbprint(t *testing.T) {
+	spec, err := jwk.ParseBytes([]byte(rfc7638Key))
+	require.NoError(t, err)
+
+	key, err := spec.ToJWK()
+	require.NoError(t, err)
+
+	canonical := `{"e":"AQAB","kty":"RSA","n":"` + rfc7638N + `"}`
+	sum := sha1.Sum([]byte(canonical))
+
+	testCases := []struct {
+		Name       string
+		Hash       crypto.Hash
+		Thumbprint string
+		Error      error
+	}{
+		{
+			Name:       "SHA256",
+			Hash:       crypto.SHA256,
+			Thumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			Name:       "SHA1",
+			Hash:       crypto.SHA1,
+			Thumbprint: base64.RawURLEncoding.EncodeToString(sum[:]),
+		},
+		{
+			Name:  "Unavailable",
+			Hash:  crypto.MD4,
+			Error: jwks.ErrUnsupportedHash,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tp, err := jwks.Thumbprint(key, tc.Hash)
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Thumbprint, tp)
+		})
+	}
+}
+
+func TestManagerFetchKeyByThumbprint(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(rawJWKSHandler(t,
+		rawKey{&keys.RSA.PublicKey, map[string]interface{}{"kid": "rsa", "x5t": "cert-sha1"}},
+		rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec", "x5t#S256": "cert-sha256"}},
+	))
+	defer ts.Close()
+
+	ecKey, err := jwk.NewSpec(&keys.ECDSA.PublicKey).ToJWK()
+	require.NoError(t, err)
+
+	ecSHA256, err := jwks.Thumbprint(ecKey, crypto.SHA256)
+	require.NoError(t, err)
+	ecSHA1, err := jwks.Thumbprint(ecKey, crypto.SHA1)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name  string
+		Fetch func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error)
+		Kid   string
+		Error error
+	}{
+		{
+			Name: "SHA256",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByThumbprint(ctx, ecSHA256, crypto.SHA256)
+			},
+			Kid: "ec",
+		},
+		{
+			Name: "SHA1",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByThumbprint(ctx, ecSHA1, crypto.SHA1)
+			},
+			Kid: "ec",
+		},
+		{
+			Name: "UnindexedHash",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByThumbprint(ctx, ecSHA256, crypto.SHA512)
+			},
+			Error: jwks.ErrUnsupportedHash,
+		},
+		{
+			Name: "UnknownThumbprint",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByThumbprint(ctx, "unknown", crypto.SHA256)
+			},
+			Error: jwks.ErrPublicKeyNotFound,
+		},
+		{
+			Name: "X5t",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByX5t(ctx, "cert-sha1")
+			},
+			Kid: "rsa",
+		},
+		{
+			Name: "X5tS256",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByX5t(ctx, "cert-sha256")
+			},
+			Kid: "ec",
+		},
+		{
+			Name: "UnknownX5t",
+			Fetch: func(ctx context.Context, mng jwks.Manager) (*jwks.JWK, error) {
+				return mng.FetchKeyByX5t(ctx, "unknown")
+			},
+			Error: jwks.ErrPublicKeyNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mng, err := jwks.NewManager(ts.URL, jwks.WithLookup(true))
+			require.NoError(t, err)
+
+			key, err := tc.Fetch(context.Background(), mng)
+			if tc.Error != nil {
+				require.ErrorIs(t, err, tc.Error)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Kid, key.Kid)
+		})
+	}
+}
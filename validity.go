@@ -0,0 +1,87 @@
+package jwks
+
+import (
+	"crypto/x509"
+	"errors"
+	"time"
+)
+
+// ErrKeyExpired raises when key is used outside of its validity window.
+var ErrKeyExpired = errors.New("jwks: key is outside of its validity window")
+
+// validity is a period when key may be used. Zero bounds are open.
+type validity struct {
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+func (v validity) contains(now time.Time) bool {
+	if !v.notBefore.IsZero() && now.Before(v.notBefore) {
+		return false
+	}
+	if !v.notAfter.IsZero() && now.After(v.notAfter) {
+		return false
+	}
+	return true
+}
+
+func (v validity) isZero() bool {
+	return v.notBefore.IsZero() && v.notAfter.IsZero()
+}
+
+// keyValidity derives validity window of key from its leaf certificate and
+// non-standard `nbf` and `exp` members. The narrowest window wins.
+func keyValidity(params keyParams, cert *x509.Certificate) validity {
+	var v validity
+
+	if cert != nil {
+		v.notBefore, v.notAfter = cert.NotBefore, cert.NotAfter
+	}
+
+	if params.Nbf > 0 {
+		if nbf := unixTime(params.Nbf); nbf.After(v.notBefore) {
+			v.notBefore = nbf
+		}
+	}
+	if params.Exp > 0 {
+		if exp := unixTime(params.Exp); v.notAfter.IsZero() || exp.Before(v.notAfter) {
+			v.notAfter = exp
+		}
+	}
+
+	return v
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
+
+// valid reports whether key of last fetched set with given kid may be used.
+// Keys unknown to last fetched set have no validity window.
+func (m *manager) valid(kid string, now time.Time) bool {
+	m.mu.RLock()
+	v, ok := m.windows[kid]
+	m.mu.RUnlock()
+
+	return !ok || v.contains(now)
+}
+
+// expiredKey identifies kid in negative cache when source keeps serving it
+// outside of its validity window.
+func expiredKey(kid string) string {
+	return "\x00expired\x00" + kid
+}
+
+// checkValidity rejects fetched key if it is outside of its validity window.
+func (m *manager) checkValidity(key *JWK, err error) (*JWK, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.valid(key.Kid, time.Now()) {
+		m.logger.Debug().Msgf("%s is outside of its validity window", key.Kid)
+		return nil, ErrKeyExpired
+	}
+
+	return key, nil
+}
@@ -0,0 +1,204 @@
+package jwks_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerKeyValidity(t *testing.T) {
+	keys := newSigningKeys(t)
+	ca := newTestCA(t)
+
+	now := time.Now()
+	hour := time.Hour
+
+	testCases := []struct {
+		Name   string
+		Params map[string]interface{}
+		Error  error
+	}{
+		{
+			Name:   "NoWindow",
+			Params: map[string]interface{}{},
+		},
+		{
+			Name:   "NotExpired",
+			Params: map[string]interface{}{"exp": now.Add(hour).Unix()},
+		},
+		{
+			Name:   "Expired",
+			Params: map[string]interface{}{"exp": now.Add(-hour).Unix()},
+			Error:  jwks.ErrKeyExpired,
+		},
+		{
+			Name:   "NotValidYet",
+			Params: map[string]interface{}{"nbf": now.Add(hour).Unix()},
+			Error:  jwks.ErrKeyExpired,
+		},
+		{
+			Name:   "ValidCertificate",
+			Params: map[string]interface{}{"x5c": []string{ca.issue(t, &keys.RSA.PublicKey, now.Add(-hour), now.Add(hour))}},
+		},
+		{
+			Name:   "ExpiredCertificate",
+			Params: map[string]interface{}{"x5c": []string{ca.issue(t, &keys.RSA.PublicKey, now.Add(-2*hour), now.Add(-hour))}},
+			Error:  jwks.ErrKeyExpired,
+		},
+		{
+			Name: "ExpiredBeforeCertificate",
+			Params: map[string]interface{}{
+				"x5c": []string{ca.issue(t, &keys.RSA.PublicKey, now.Add(-hour), now.Add(hour))},
+				"exp": now.Add(-time.Minute).Unix(),
+			},
+			Error: jwks.ErrKeyExpired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			params := map[string]interface{}{"kid": "rsa"}
+			for k, v := range tc.Params {
+				params[k] = v
+			}
+
+			ts := httptest.NewServer(rawJWKSHandler(t, rawKey{&keys.RSA.PublicKey, params}))
+			defer ts.Close()
+
+			mng, err := jwks.NewManager(ts.URL)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+
+			for i := 0; i < 2; i++ {
+				key, err := mng.FetchKey(ctx, "rsa")
+				if tc.Error != nil {
+					require.ErrorIs(t, err, tc.Error)
+				} else {
+					require.NoError(t, err)
+					require.Equal(t, "rsa", key.Kid)
+				}
+
+				found, err := mng.FetchKeys(ctx, jwks.KeyCriteria{Kty: "RSA"})
+				if tc.Error != nil {
+					require.ErrorIs(t, err, jwks.ErrPublicKeyNotFound)
+				} else {
+					require.NoError(t, err)
+					require.Len(t, found, 1)
+				}
+			}
+		})
+	}
+}
+
+func TestManagerRefreshesExpiredKey(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	expired := rawJWKSHandler(t, rawKey{&keys.RSA.PublicKey, map[string]interface{}{
+		"kid": "rsa", "exp": time.Now().Add(-time.Minute).Unix(),
+	}})
+	rotated := rawJWKSHandler(t, rawKey{&keys.RSA.PublicKey, map[string]interface{}{
+		"kid": "rsa", "exp": time.Now().Add(time.Hour).Unix(),
+	}})
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			expired.ServeHTTP(w, r)
+			return
+		}
+		rotated.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	mng, err := jwks.NewManager(ts.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = mng.FetchKey(ctx, "rsa")
+	require.ErrorIs(t, err, jwks.ErrKeyExpired)
+
+	// Cached key is expired, so key set is downloaded again.
+	key, err := mng.FetchKey(ctx, "rsa")
+	require.NoError(t, err)
+	require.Equal(t, "rsa", key.Kid)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	_, err = mng.FetchKey(ctx, "rsa")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestManagerRemembersExpiredKey(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	handler := rawJWKSHandler(t, rawKey{&keys.RSA.PublicKey, map[string]interface{}{
+		"kid": "rsa", "exp": time.Now().Add(-time.Minute).Unix(),
+	}})
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	mng, err := jwks.NewManager(ts.URL, jwks.WithNegativeCacheTTL(time.Minute))
+	r.NoError(err)
+
+	for i := 0; i < 5; i++ {
+		_, err = mng.FetchKey(context.Background(), "rsa")
+		r.ErrorIs(err, jwks.ErrKeyExpired)
+	}
+
+	// Initial download and single refetch of expired key.
+	r.Equal(int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestManagerDropsExpiredKeyAfterRotation(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	expiring := rawJWKSHandler(t, rawKey{&keys.RSA.PublicKey, map[string]interface{}{
+		"kid": "rsa", "exp": time.Now().Add(time.Second).Unix(),
+	}})
+	rotated := rawJWKSHandler(t, rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec"}})
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			expiring.ServeHTTP(w, req)
+			return
+		}
+		rotated.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	mng, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	ctx := context.Background()
+
+	_, err = mng.FetchKey(ctx, "rsa")
+	r.NoError(err)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	// Expired key has been removed from source, so it is not served again.
+	for i := 0; i < 2; i++ {
+		_, err = mng.FetchKey(ctx, "rsa")
+		r.ErrorIs(err, jwks.ErrPublicKeyNotFound)
+	}
+
+	size, err := mng.CacheSize(ctx)
+	r.NoError(err)
+	r.Equal(1, size)
+}
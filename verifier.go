@@ -0,0 +1,282 @@
+package jwks
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+
+	// Register hash functions used by supported algorithms.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	// ErrMalformedToken raises when token is not a valid compact JWS.
+	ErrMalformedToken = errors.New("jwks: malformed token")
+	// ErrAlgorithmNotAllowed raises when token algorithm is not in allowed list.
+	ErrAlgorithmNotAllowed = errors.New("jwks: algorithm is not allowed")
+	// ErrKeyMismatch raises when key cannot be used with token algorithm.
+	ErrKeyMismatch = errors.New("jwks: key does not match algorithm")
+	// ErrInvalidSignature raises when token signature verification has been failed.
+	ErrInvalidSignature = errors.New("jwks: invalid signature")
+)
+
+// Header represents JOSE header of token.
+type Header struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid,omitempty"`
+	Typ  string   `json:"typ,omitempty"`
+	Cty  string   `json:"cty,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+}
+
+// Claims represents token payload. Numbers are decoded as `json.Number`.
+type Claims map[string]interface{}
+
+// Token represents verified token.
+type Token struct {
+	Raw    string
+	Header Header
+	Claims Claims
+	Key    *JWK
+}
+
+type algorithm struct {
+	kty  string
+	hash crypto.Hash
+	crv  string
+	pss  bool
+}
+
+var algorithms = map[string]algorithm{
+	"RS256": {kty: "RSA", hash: crypto.SHA256},
+	"RS384": {kty: "RSA", hash: crypto.SHA384},
+	"RS512": {kty: "RSA", hash: crypto.SHA512},
+	"PS256": {kty: "RSA", hash: crypto.SHA256, pss: true},
+	"PS384": {kty: "RSA", hash: crypto.SHA384, pss: true},
+	"PS512": {kty: "RSA", hash: crypto.SHA512, pss: true},
+	"ES256": {kty: "EC", hash: crypto.SHA256, crv: "P-256"},
+	"ES384": {kty: "EC", hash: crypto.SHA384, crv: "P-384"},
+	"ES512": {kty: "EC", hash: crypto.SHA512, crv: "P-521"},
+	"EdDSA": {kty: "OKP", crv: "Ed25519"},
+}
+
+// Verifier verifies token signature using keys of `Manager`.
+type Verifier struct {
+	manager    Manager
+	algorithms map[string]bool
+	claims     *ClaimsValidator
+}
+
+// VerifierOption is used for configuring verifier.
+type VerifierOption func(v *Verifier)
+
+// WithAlgorithms restricts allowed signature algorithms.
+// Default is every supported algorithm: `RS*`, `PS*`, `ES*` and `EdDSA`.
+func WithAlgorithms(algs ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.algorithms = make(map[string]bool, len(algs))
+		for _, alg := range algs {
+			if _, ok := algorithms[alg]; ok {
+				v.algorithms[alg] = true
+			}
+		}
+	}
+}
+
+// WithClaimsValidator enables validation of registered claims after signature check.
+func WithClaimsValidator(cv *ClaimsValidator) VerifierOption {
+	return func(v *Verifier) { v.claims = cv }
+}
+
+// NewVerifier returns a new instance of `Verifier`.
+func NewVerifier(m Manager, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		manager:    m,
+		algorithms: make(map[string]bool, len(algorithms)),
+	}
+
+	for alg := range algorithms {
+		v.algorithms[alg] = true
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Verify parses compact serialized token, resolves its key by kid or
+// by algorithm if kid is absent and checks signature. Claims are validated only if `WithClaimsValidator` is set.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header Header
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	// Extensions are not supported, so critical ones must be rejected.
+	if len(header.Crit) > 0 {
+		return nil, ErrMalformedToken
+	}
+
+	if !v.algorithms[header.Alg] {
+		return nil, ErrAlgorithmNotAllowed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	signingInput := token[:len(parts[0])+1+len(parts[1])]
+
+	key, err := v.verify(ctx, header, signingInput, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if v.claims != nil {
+		if err := v.claims.Validate(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Token{
+		Raw:    token,
+		Header: header,
+		Claims: claims,
+		Key:    key,
+	}, nil
+}
+
+// verify checks signature with key referenced by kid. If token has no kid,
+// every key matching algorithm is tried in order.
+func (v *Verifier) verify(ctx context.Context, header Header, signingInput string, signature []byte) (*JWK, error) {
+	if header.Kid != "" {
+		key, pub, err := fetchKeyPair(ctx, v.manager, header.Kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := verifySignature(header.Alg, key, pub, signingInput, signature); err != nil {
+			return nil, err
+		}
+
+		return key, nil
+	}
+
+	alg := algorithms[header.Alg]
+	candidates, err := v.manager.FetchKeys(ctx, KeyCriteria{
+		Alg:    header.Alg,
+		Kty:    alg.kty,
+		Crv:    alg.crv,
+		Use:    "sig",
+		KeyOps: []string{"verify"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range candidates {
+		pub, err := parsePublicKey(v.manager, key)
+		if err != nil {
+			continue
+		}
+
+		if err := verifySignature(header.Alg, key, pub, signingInput, signature); err == nil {
+			return key, nil
+		}
+	}
+
+	return nil, ErrInvalidSignature
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
+
+func verifySignature(alg string, key *JWK, pub crypto.PublicKey, signingInput string, signature []byte) error {
+	spec, ok := algorithms[alg]
+	if !ok {
+		return ErrAlgorithmNotAllowed
+	}
+
+	if key.Kty != spec.kty || (spec.crv != "" && key.Crv != spec.crv) {
+		return ErrKeyMismatch
+	}
+	if key.Alg != "" && key.Alg != alg {
+		return ErrKeyMismatch
+	}
+	if key.Use != "" && key.Use != "sig" {
+		return ErrKeyMismatch
+	}
+
+	var digest []byte
+	if spec.hash != 0 {
+		h := spec.hash.New()
+		h.Write([]byte(signingInput))
+		digest = h.Sum(nil)
+	}
+
+	var err error
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if spec.pss {
+			err = rsa.VerifyPSS(k, spec.hash, digest, signature, &rsa.PSSOptions{
+				SaltLength: rsa.PSSSaltLengthEqualsHash,
+			})
+		} else {
+			err = rsa.VerifyPKCS1v15(k, spec.hash, digest, signature)
+		}
+		if err != nil {
+			return ErrInvalidSignature
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrInvalidSignature
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, []byte(signingInput), signature) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrKeyMismatch
+	}
+
+	return nil
+}
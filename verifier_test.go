@@ -0,0 +1,225 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/danikarik/jwks"
+	"github.com/rakutentech/jwk-go/okp"
+	"github.com/stretchr/testify/require"
+)
+
+type signingKeys struct {
+	RSA     *rsa.PrivateKey
+	ECDSA   *ecdsa.PrivateKey
+	Ed25519 ed25519.PrivateKey
+}
+
+func newSigningKeys(t *testing.T) *signingKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return &signingKeys{RSA: rsaKey, ECDSA: ecKey, Ed25519: edKey}
+}
+
+func (sk *signingKeys) testKeys() []testKey {
+	pub := sk.Ed25519.Public().(ed25519.PublicKey)
+
+	return []testKey{
+		{"rsa", &sk.RSA.PublicKey},
+		{"ec", &sk.ECDSA.PublicKey},
+		{"ed", okp.NewEd25519(pub, nil)},
+	}
+}
+
+func signToken(t *testing.T, header, claims map[string]interface{}, key crypto.Signer) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := encode(header) + "." + encode(claims)
+
+	var (
+		sig []byte
+		err error
+	)
+
+	alg := header["alg"].(string)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		h := crypto.SHA256.New()
+		h.Write([]byte(signingInput))
+		if alg[0] == 'P' {
+			sig, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, h.Sum(nil), &rsa.PSSOptions{
+				SaltLength: rsa.PSSSaltLengthEqualsHash,
+			})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h.Sum(nil))
+		}
+	case *ecdsa.PrivateKey:
+		h := crypto.SHA256.New()
+		h.Write([]byte(signingInput))
+		r, s, serr := ecdsa.Sign(rand.Reader, k, h.Sum(nil))
+		err = serr
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signingInput))
+	}
+	require.NoError(t, err)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifierVerify(t *testing.T) {
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(jwksHandler(keys.testKeys()...))
+	defer ts.Close()
+
+	claims := map[string]interface{}{"sub": "user", "exp": 1700000000}
+
+	testCases := []struct {
+		Name    string
+		Token   string
+		Options []jwks.VerifierOption
+		Error   error
+	}{
+		{
+			Name:  "RS256",
+			Token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims, keys.RSA),
+		},
+		{
+			Name:  "PS256",
+			Token: signToken(t, map[string]interface{}{"alg": "PS256", "kid": "rsa"}, claims, keys.RSA),
+		},
+		{
+			Name:  "ES256",
+			Token: signToken(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, claims, keys.ECDSA),
+		},
+		{
+			Name:  "EdDSA",
+			Token: signToken(t, map[string]interface{}{"alg": "EdDSA", "kid": "ed"}, claims, keys.Ed25519),
+		},
+		{
+			Name:    "NotAllowed",
+			Token:   signToken(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, claims, keys.ECDSA),
+			Options: []jwks.VerifierOption{jwks.WithAlgorithms("RS256")},
+			Error:   jwks.ErrAlgorithmNotAllowed,
+		},
+		{
+			Name:  "None",
+			Token: "eyJhbGciOiJub25lIiwia2lkIjoicnNhIn0.eyJzdWIiOiJ1c2VyIn0.",
+			Error: jwks.ErrAlgorithmNotAllowed,
+		},
+		{
+			Name:  "KeyMismatch",
+			Token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "ec"}, claims, keys.RSA),
+			Error: jwks.ErrKeyMismatch,
+		},
+		{
+			Name:  "WrongKey",
+			Token: signToken(t, map[string]interface{}{"alg": "EdDSA", "kid": "ed"}, claims, newSigningKeys(t).Ed25519),
+			Error: jwks.ErrInvalidSignature,
+		},
+		{
+			Name:  "UnknownKey",
+			Token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "other"}, claims, keys.RSA),
+			Error: jwks.ErrPublicKeyNotFound,
+		},
+		{
+			Name:  "Critical",
+			Token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa", "crit": []string{"exp"}}, claims, keys.RSA),
+			Error: jwks.ErrMalformedToken,
+		},
+		{
+			Name:  "Malformed",
+			Token: "not.a.token",
+			Error: jwks.ErrMalformedToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := require.New(t)
+
+			manager, err := jwks.NewManager(ts.URL)
+			r.NoError(err)
+
+			verifier := jwks.NewVerifier(manager, tc.Options...)
+
+			token, err := verifier.Verify(context.Background(), tc.Token)
+			if tc.Error != nil {
+				r.ErrorIs(err, tc.Error)
+			} else {
+				r.NoError(err)
+				r.Equal("user", token.Claims["sub"])
+				r.Equal(json.Number("1700000000"), token.Claims["exp"])
+			}
+		})
+	}
+}
+
+func TestVerifierTamperedPayload(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	ts := httptest.NewServer(jwksHandler(keys.testKeys()...))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL)
+	r.NoError(err)
+
+	token := signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{"sub": "user"}, keys.RSA)
+	other := signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{"sub": "admin"}, keys.RSA)
+
+	first, second := strings.Split(token, "."), strings.Split(other, ".")
+	tampered := first[0] + "." + second[1] + "." + first[2]
+
+	_, err = jwks.NewVerifier(manager).Verify(context.Background(), tampered)
+	r.ErrorIs(err, jwks.ErrInvalidSignature)
+}
+
+func TestVerifierSingleDownload(t *testing.T) {
+	r := require.New(t)
+	keys := newSigningKeys(t)
+
+	handler := jwksHandler(keys.testKeys()...)
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	manager, err := jwks.NewManager(ts.URL, jwks.WithLookup(false))
+	r.NoError(err)
+
+	token := signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, map[string]interface{}{"sub": "user"}, keys.RSA)
+
+	_, err = jwks.NewVerifier(manager).Verify(context.Background(), token)
+	r.NoError(err)
+	r.Equal(int32(1), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,104 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCertificateNotFound raises when key has no `x5c` certificate chain.
+	ErrCertificateNotFound = errors.New("jwks: certificate not found")
+	// ErrInvalidCertificateChain raises when `x5c` chain cannot be parsed or verified.
+	ErrInvalidCertificateChain = errors.New("jwks: invalid certificate chain")
+	// ErrCertificateMismatch raises when leaf certificate does not hold key.
+	ErrCertificateMismatch = errors.New("jwks: certificate does not match key")
+)
+
+// parseChain decodes `x5c` member. Certificates are standard base64 encoded DER.
+func parseChain(chain []string) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0, len(chain))
+
+	for _, raw := range chain {
+		der, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
+
+// certificate returns leaf certificate of key. Chain is verified only if
+// `WithX5CValidation` is set, otherwise invalid chain is ignored.
+func (m *manager) certificate(chain []string, pub crypto.PublicKey) (*x509.Certificate, error) {
+	if len(chain) == 0 {
+		if m.x5cOptions != nil {
+			return nil, ErrCertificateNotFound
+		}
+		return nil, nil
+	}
+
+	leaf, err := m.verifyChain(chain, pub)
+	if err != nil && m.x5cOptions == nil {
+		m.logger.Debug().Msgf("ignoring certificate chain with %v", err)
+		return nil, nil
+	}
+
+	return leaf, err
+}
+
+func (m *manager) verifyChain(chain []string, pub crypto.PublicKey) (*x509.Certificate, error) {
+	certs, err := parseChain(chain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificateChain, err)
+	}
+
+	leaf := certs[0]
+
+	k, ok := pub.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !k.Equal(leaf.PublicKey) {
+		return nil, ErrCertificateMismatch
+	}
+
+	if m.x5cOptions == nil {
+		return leaf, nil
+	}
+
+	opts := *m.x5cOptions
+	opts.Intermediates = x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(opts); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificateChain, err)
+	}
+
+	return leaf, nil
+}
+
+// Certificate returns parsed leaf certificate of `x5c` chain of key.
+func (m *manager) Certificate(ctx context.Context, kid string) (*x509.Certificate, error) {
+	if _, err := m.FetchKey(ctx, kid); err != nil {
+		return nil, err
+	}
+
+	for _, e := range m.getEntries() {
+		if e.key.Kid == kid && e.cert != nil {
+			return e.cert, nil
+		}
+	}
+
+	return nil, ErrCertificateNotFound
+}
@@ -0,0 +1,170 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danikarik/jwks"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns base64 encoded leaf certificate for public key.
+func (ca *testCA) issue(t *testing.T, pub crypto.PublicKey, notBefore, notAfter time.Time) string {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "Test Signer"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func TestManagerX5CValidation(t *testing.T) {
+	keys := newSigningKeys(t)
+	ca := newTestCA(t)
+	other := newTestCA(t)
+
+	now := time.Now()
+	valid := ca.issue(t, &keys.RSA.PublicKey, now.Add(-time.Hour), now.Add(time.Hour))
+
+	testCases := []struct {
+		Name     string
+		Params   map[string]interface{}
+		Validate bool
+		Error    error
+	}{
+		{
+			Name:     "ValidChain",
+			Params:   map[string]interface{}{"x5c": []string{valid}},
+			Validate: true,
+		},
+		{
+			Name:     "UntrustedRoot",
+			Params:   map[string]interface{}{"x5c": []string{other.issue(t, &keys.RSA.PublicKey, now.Add(-time.Hour), now.Add(time.Hour))}},
+			Validate: true,
+			Error:    jwks.ErrInvalidCertificateChain,
+		},
+		{
+			Name:     "ExpiredCertificate",
+			Params:   map[string]interface{}{"x5c": []string{ca.issue(t, &keys.RSA.PublicKey, now.Add(-2*time.Hour), now.Add(-time.Hour))}},
+			Validate: true,
+			Error:    jwks.ErrInvalidCertificateChain,
+		},
+		{
+			Name:     "MismatchedKey",
+			Params:   map[string]interface{}{"x5c": []string{ca.issue(t, &keys.ECDSA.PublicKey, now.Add(-time.Hour), now.Add(time.Hour))}},
+			Validate: true,
+			Error:    jwks.ErrCertificateMismatch,
+		},
+		{
+			Name:     "MalformedChain",
+			Params:   map[string]interface{}{"x5c": []string{"not a certificate"}},
+			Validate: true,
+			Error:    jwks.ErrInvalidCertificateChain,
+		},
+		{
+			Name:     "MissingChain",
+			Validate: true,
+			Error:    jwks.ErrCertificateNotFound,
+		},
+		{
+			Name:   "WithoutValidation",
+			Params: map[string]interface{}{"x5c": []string{valid}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			params := map[string]interface{}{"kid": "rsa"}
+			for k, v := range tc.Params {
+				params[k] = v
+			}
+
+			ts := httptest.NewServer(rawJWKSHandler(t,
+				rawKey{&keys.RSA.PublicKey, params},
+				rawKey{&keys.ECDSA.PublicKey, map[string]interface{}{"kid": "ec"}},
+			))
+			defer ts.Close()
+
+			var report jwks.FetchReport
+			opts := []jwks.Option{
+				jwks.WithFetchHook(func(r jwks.FetchReport) { report = r }),
+			}
+			if tc.Validate {
+				opts = append(opts, jwks.WithX5CValidation(ca.pool, x509.VerifyOptions{}))
+			}
+
+			mng, err := jwks.NewManager(ts.URL, opts...)
+			require.NoError(t, err)
+
+			cert, err := mng.Certificate(context.Background(), "rsa")
+			if tc.Error != nil {
+				require.ErrorIs(t, err, jwks.ErrPublicKeyNotFound)
+				require.Len(t, report.Skipped, 2)
+				require.Equal(t, "rsa", report.Skipped[0].Kid)
+				require.ErrorIs(t, report.Skipped[0].Err, tc.Error)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "Test Signer", cert.Subject.CommonName)
+
+			// Key without chain is rejected only if validation is enabled.
+			_, err = mng.Certificate(context.Background(), "ec")
+			if tc.Validate {
+				require.ErrorIs(t, err, jwks.ErrPublicKeyNotFound)
+				require.Len(t, report.Skipped, 1)
+				require.ErrorIs(t, report.Skipped[0].Err, jwks.ErrCertificateNotFound)
+			} else {
+				require.ErrorIs(t, err, jwks.ErrCertificateNotFound)
+				require.Empty(t, report.Skipped)
+			}
+		})
+	}
+}